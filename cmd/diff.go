@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/can3p/sackmesser/pkg/cobrahelpers"
+	"github.com/can3p/sackmesser/pkg/diff"
+	"github.com/can3p/sackmesser/pkg/sackmesser"
+	"github.com/can3p/sackmesser/pkg/traverse/types"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func DiffCommand() *cobra.Command {
+	var fromFormat string
+	var toFormat string
+	var jsonPatch bool
+	var arrayStrategy string
+	var arrayKey string
+
+	var diffCmd = &cobra.Command{
+		Use:   "diff <from-file> <to-file>",
+		Short: "print operations that turn one document into another",
+		Long: `Compare two documents and print operations that turn the first one into the second
+
+Operations are printed one per line in the same syntax mod command accepts,
+with --json-patch the difference is printed as RFC 6902 JSON Patch instead.
+Formats of the files are guessed by their extensions unless specified explicitly,
+documents of different formats can be compared.
+
+Arrays are compared index by index by default. lcs strategy keeps the longest
+common subsequence of elements and inserts or removes the rest, key strategy
+matches objects by the field given with --array-key and can move them. Inserts
+and moves can only be expressed with JSON Patch.
+
+Multi-document yaml inputs are compared document by document, operations
+of every changed document are printed after a "# document N" comment.
+Both inputs should have the same number of documents and JSON Patch can only
+describe a single document.`,
+		Args:         cobra.ExactArgs(2),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts := []diff.Option{diff.WithArrayStrategy(diff.ArrayStrategy(arrayStrategy))}
+
+			if arrayKey != "" {
+				if cmd.Flags().Changed("array-strategy") && arrayStrategy != string(diff.ArraysByKey) {
+					return errors.Errorf("--array-key only makes sense with key array strategy")
+				}
+
+				opts = append(opts, diff.WithArrayKey(arrayKey))
+			}
+
+			from, err := readDocuments(args[0], fromFormat)
+
+			if err != nil {
+				return err
+			}
+
+			to, err := readDocuments(args[1], toFormat)
+
+			if err != nil {
+				return err
+			}
+
+			if len(from) != len(to) {
+				return errors.Errorf("%s has %d document(s) and %s has %d, documents are compared one by one", args[0], len(from), args[1], len(to))
+			}
+
+			var out []byte
+
+			switch {
+			case len(from) == 1 && jsonPatch:
+				out, err = diffPatch(from[0], to[0], opts)
+			case len(from) == 1:
+				out, err = diffOperations(from[0], to[0], opts)
+			case jsonPatch:
+				return errors.Errorf("JSON Patch can only describe a single document, the inputs have %d", len(from))
+			default:
+				out, err = diffDocuments(from, to, opts)
+			}
+
+			if err != nil {
+				return err
+			}
+
+			_, err = cmd.OutOrStdout().Write(out)
+			return err
+		},
+	}
+
+	diffCmd.Flags().Var(cobrahelpers.NewEnumFlag(&fromFormat, "", sackmesser.Formats...), "from-format", `format of the first file: json, yaml or toml`)
+	diffCmd.Flags().Var(cobrahelpers.NewEnumFlag(&toFormat, "", sackmesser.Formats...), "to-format", `format of the second file: json, yaml or toml`)
+
+	diffCmd.Flags().BoolVar(&jsonPatch, "json-patch", false, "print the difference as JSON Patch")
+	diffCmd.Flags().Var(cobrahelpers.NewEnumFlag(&arrayStrategy, string(diff.ArraysByIndex), diff.ArrayStrategies...), "array-strategy", `the way arrays are compared: index, lcs or key`)
+	diffCmd.Flags().StringVar(&arrayKey, "array-key", "", "field to match array elements by, implies key array strategy")
+
+	return diffCmd
+}
+
+func diffOperations(from types.Node, to types.Node, opts []diff.Option) ([]byte, error) {
+	ops, err := diff.Operations(from, to, opts...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+
+	for _, op := range ops {
+		buf.WriteString(op.Expression())
+		buf.WriteRune('\n')
+	}
+
+	return buf.Bytes(), nil
+}
+
+// diffDocuments prints operations of every changed document
+// after a comment with the index of the document
+func diffDocuments(from []types.Node, to []types.Node, opts []diff.Option) ([]byte, error) {
+	var buf bytes.Buffer
+
+	for idx := range from {
+		out, err := diffOperations(from[idx], to[idx], opts)
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "document %d", idx)
+		}
+
+		if len(out) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(&buf, "# document %d\n", idx)
+		buf.Write(out)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func diffPatch(from types.Node, to types.Node, opts []diff.Option) ([]byte, error) {
+	patch, err := diff.Patch(from, to, opts...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+
+	if err := enc.Encode(patch); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func readDocuments(fname string, format string) ([]types.Node, error) {
+	if format == "" {
+		format = sackmesser.FormatFromFilename(fname)
+	}
+
+	input, err := os.ReadFile(fname)
+
+	if err != nil {
+		return nil, err
+	}
+
+	docs, err := sackmesser.ParseDocuments(input, format)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s", fname)
+	}
+
+	return docs, nil
+}
+
+func init() {
+	rootCmd.AddCommand(DiffCommand())
+}
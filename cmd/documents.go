@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"github.com/can3p/sackmesser/pkg/operations"
+	"github.com/can3p/sackmesser/pkg/sackmesser"
+	"github.com/can3p/sackmesser/pkg/traverse/types"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// documentSelector picks documents of a multi-document
+// input to work with, all documents are selected by default
+type documentSelector struct {
+	indices   []int
+	predicate types.Predicate
+}
+
+func addDocumentFlags(cmd *cobra.Command, indices *[]int, filter *string) {
+	cmd.Flags().IntSliceVar(indices, "doc", nil, "work only with documents with these indices (starting from 0) of a multi-document yaml input, can be repeated")
+	cmd.Flags().StringVar(filter, "doc-filter", "", `work only with documents of a multi-document yaml input matching the condition, e.g. 'kind == "Deployment"'`)
+}
+
+func newDocumentSelector(indices []int, filter string) (*documentSelector, error) {
+	sel := &documentSelector{
+		indices: indices,
+	}
+
+	if filter == "" {
+		return sel, nil
+	}
+
+	predicate, err := operations.NewParser().ParsePredicate(filter)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid document filter: [%s]", filter)
+	}
+
+	sel.predicate = predicate
+
+	return sel, nil
+}
+
+// selected returns documents that match both the indices and the filter
+func (s *documentSelector) selected(docs []types.Node) ([]types.Node, error) {
+	return sackmesser.SelectDocuments(docs, s.indices, s.predicate)
+}
@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/hexops/gotextdiff"
+	"github.com/hexops/gotextdiff/myers"
+	"github.com/hexops/gotextdiff/span"
+	"github.com/mattn/go-isatty"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// exitCodeChanged is used by dry run to signal that the input
+// would be modified, failures keep the default exit code 1
+const exitCodeChanged = 2
+
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+)
+
+var colorModes = []string{colorAuto, colorAlways, colorNever}
+
+const (
+	ansiReset = "\x1b[0m"
+	ansiBold  = "\x1b[1m"
+	ansiRed   = "\x1b[31m"
+	ansiGreen = "\x1b[32m"
+	ansiCyan  = "\x1b[36m"
+)
+
+// dryRunFiles prints a unified diff between the original and transformed
+// version of every file or stdin if there are no files, nothing is written.
+// The original is passed through the transform without operations
+// to make sure that only the changes made by operations are shown
+func dryRunFiles(cmd *cobra.Command, files []string, original transformFunc, transform transformFunc, colorMode string) error {
+	// changes are not a usage error
+	cmd.SilenceUsage = true
+	colored := useColor(cmd.OutOrStdout(), colorMode)
+
+	if len(files) == 0 {
+		input, err := io.ReadAll(os.Stdin)
+
+		if err != nil {
+			return err
+		}
+
+		changed, err := dryRun(cmd.OutOrStdout(), "stdin", input, original, transform, colored)
+
+		if err != nil {
+			return err
+		}
+
+		if changed {
+			return &exitError{code: exitCodeChanged, err: errors.Errorf("input would be changed")}
+		}
+
+		return nil
+	}
+
+	failed := 0
+	changed := 0
+
+	for _, fname := range files {
+		input, err := os.ReadFile(fname)
+
+		if err == nil {
+			var fileChanged bool
+
+			fileChanged, err = dryRun(cmd.OutOrStdout(), fname, input, original, transform, colored)
+
+			if fileChanged {
+				changed++
+			}
+		}
+
+		if err != nil {
+			failed++
+			fmt.Fprintf(cmd.ErrOrStderr(), "%s: %s\n", fname, err.Error())
+		}
+	}
+
+	if failed > 0 {
+		return errors.Errorf("failed to process %d out of %d files", failed, len(files))
+	}
+
+	if changed > 0 {
+		return &exitError{code: exitCodeChanged, err: errors.Errorf("%d out of %d files would be changed", changed, len(files))}
+	}
+
+	return nil
+}
+
+func dryRun(out io.Writer, name string, input []byte, original transformFunc, transform transformFunc, colored bool) (bool, error) {
+	before, err := runTransform(original, name, input)
+
+	if err != nil {
+		return false, err
+	}
+
+	after, err := runTransform(transform, name, input)
+
+	if err != nil {
+		return false, err
+	}
+
+	if bytes.Equal(before, after) {
+		return false, nil
+	}
+
+	edits := myers.ComputeEdits(span.URIFromPath(name), string(before), string(after))
+	unified := fmt.Sprint(gotextdiff.ToUnified("a/"+name, "b/"+name, string(before), edits))
+
+	if colored {
+		unified = colorize(unified)
+	}
+
+	_, err = io.WriteString(out, unified)
+	return true, err
+}
+
+func runTransform(transform transformFunc, name string, input []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	if err := transform(&namedReader{Reader: bytes.NewReader(input), name: name}, &buf); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func useColor(out io.Writer, colorMode string) bool {
+	switch colorMode {
+	case colorAlways:
+		return true
+	case colorNever:
+		return false
+	}
+
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+
+	f, ok := out.(*os.File)
+
+	return ok && isatty.IsTerminal(f.Fd())
+}
+
+func colorize(unified string) string {
+	var buf strings.Builder
+
+	for _, line := range strings.SplitAfter(unified, "\n") {
+		if line == "" {
+			continue
+		}
+
+		content := strings.TrimSuffix(line, "\n")
+		color := ""
+
+		switch {
+		case strings.HasPrefix(content, "---"), strings.HasPrefix(content, "+++"):
+			color = ansiBold
+		case strings.HasPrefix(content, "@@"):
+			color = ansiCyan
+		case strings.HasPrefix(content, "-"):
+			color = ansiRed
+		case strings.HasPrefix(content, "+"):
+			color = ansiGreen
+		}
+
+		if color == "" {
+			buf.WriteString(line)
+			continue
+		}
+
+		buf.WriteString(color)
+		buf.WriteString(content)
+		buf.WriteString(ansiReset)
+		buf.WriteString(line[len(content):])
+	}
+
+	return buf.String()
+}
@@ -0,0 +1,263 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/can3p/sackmesser/pkg/cobrahelpers"
+	"github.com/can3p/sackmesser/pkg/sackmesser"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+type transformFunc func(in io.Reader, out io.Writer) error
+
+// fileFlags are shared by the commands that modify their input
+type fileFlags struct {
+	inputFormat  string
+	outputFormat string
+	inPlace      bool
+	backupSuffix string
+	rewriteToml  bool
+}
+
+func addFileFlags(cmd *cobra.Command, f *fileFlags) {
+	cmd.Flags().Var(cobrahelpers.NewEnumFlag(&f.inputFormat, "json", sackmesser.Formats...), "input-format", `input format: json, yaml or toml`)
+	cmd.Flags().Var(cobrahelpers.NewEnumFlag(&f.outputFormat, "json", sackmesser.Formats...), "output-format", `output format: json, yaml or toml`)
+	cmd.Flags().BoolVarP(&f.inPlace, "in-place", "i", false, "rewrite input files instead of printing the result")
+	cmd.Flags().StringVar(&f.backupSuffix, "backup", "", "keep a copy of every file modified in place with this suffix, e.g. .bak")
+	cmd.Flags().BoolVar(&f.rewriteToml, "rewrite-toml", false, "allow rewriting toml files in place, comments, key order and formatting of the files are lost")
+}
+
+// validateInPlace checks that the flags make sense for the files,
+// with --in-place the output format defaults to the input format
+func (f *fileFlags) validateInPlace(cmd *cobra.Command, files []string) error {
+	if f.inPlace && len(files) == 0 {
+		return errors.Errorf("--in-place requires at least one input file")
+	}
+
+	if f.backupSuffix != "" && !f.inPlace {
+		return errors.Errorf("--backup only makes sense together with --in-place")
+	}
+
+	if f.inPlace && !cmd.Flags().Changed("output-format") {
+		f.outputFormat = f.inputFormat
+	}
+
+	// toml output is generated from scratch
+	if f.inPlace && f.outputFormat == "toml" && !f.rewriteToml {
+		return errors.Errorf("toml files are rewritten from scratch: comments, key order and formatting are lost, pass --rewrite-toml to do it anyway")
+	}
+
+	return nil
+}
+
+// bufferedTransform is a helper for transformations
+// that need the whole input to do their job
+func bufferedTransform(transform func(input []byte) ([]byte, error)) transformFunc {
+	return func(in io.Reader, out io.Writer) error {
+		input, err := io.ReadAll(in)
+
+		if err != nil {
+			return err
+		}
+
+		result, err := transform(input)
+
+		if err != nil {
+			return err
+		}
+
+		_, err = out.Write(result)
+		return err
+	}
+}
+
+// processFiles runs transform against every file or stdin if there are no files.
+// A failure in one file does not stop the processing of others, every error is reported
+// to stderr and the command fails once all the files have been processed.
+// A file is rewritten only when transformation succeeded for it. Without --in-place
+// the results of several files are printed one after another, every one of them on
+// new line and separated with sep
+func processFiles(cmd *cobra.Command, files []string, transform transformFunc, inPlace bool, backupSuffix string, sep []byte) error {
+	if len(files) == 0 {
+		return transform(os.Stdin, cmd.OutOrStdout())
+	}
+
+	failed := 0
+	printed := 0
+	// exit code is kept only if all the failures agree on it
+	exitCode := 0
+
+	for _, fname := range files {
+		var err error
+
+		switch {
+		case inPlace:
+			err = processFileInPlace(fname, transform, backupSuffix)
+		case len(files) == 1:
+			err = processFile(fname, transform, cmd.OutOrStdout())
+		default:
+			var buf bytes.Buffer
+
+			if err = processFile(fname, transform, &buf); err == nil {
+				err = printResult(cmd.OutOrStdout(), buf.Bytes(), printed > 0, sep)
+				printed++
+			}
+		}
+
+		if err != nil {
+			failed++
+			fmt.Fprintf(cmd.ErrOrStderr(), "%s: %s\n", fname, err.Error())
+
+			code := 1
+			var exitErr *exitError
+			if errors.As(err, &exitErr) {
+				code = exitErr.code
+			}
+
+			if failed == 1 || exitCode == code {
+				exitCode = code
+			} else {
+				exitCode = 1
+			}
+		}
+	}
+
+	if failed > 0 {
+		return &exitError{
+			code: exitCode,
+			err:  errors.Errorf("failed to process %d out of %d files", failed, len(files)),
+		}
+	}
+
+	return nil
+}
+
+// outputSeparator returns what goes between the results of several files
+// for them to be read back as a stream of documents
+func outputSeparator(outputFormat string, files []string, inPlace bool) ([]byte, error) {
+	switch outputFormat {
+	case "yaml":
+		return []byte("---\n"), nil
+	case "toml":
+		if len(files) > 1 && !inPlace {
+			return nil, errors.Errorf("toml output of several files cannot be printed as one document, use --in-place")
+		}
+	}
+
+	return nil, nil
+}
+
+func printResult(w io.Writer, result []byte, separate bool, sep []byte) error {
+	if !bytes.HasSuffix(result, []byte("\n")) {
+		result = append(result, '\n')
+	}
+
+	// a yaml output might already start with a document marker
+	if separate && len(sep) > 0 && !bytes.HasPrefix(result, sep) {
+		if _, err := w.Write(sep); err != nil {
+			return err
+		}
+	}
+
+	_, err := w.Write(result)
+	return err
+}
+
+func processFile(fname string, transform transformFunc, out io.Writer) error {
+	f, err := os.Open(fname)
+
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = f.Close() }()
+
+	return transform(f, out)
+}
+
+// processFileInPlace keeps the trailing newline of the file
+// even if the output format does not end with it
+func processFileInPlace(fname string, transform transformFunc, backupSuffix string) error {
+	source, err := os.ReadFile(fname)
+
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+
+	if err := transform(&namedReader{Reader: bytes.NewReader(source), name: fname}, &buf); err != nil {
+		return err
+	}
+
+	result := buf.Bytes()
+
+	if bytes.HasSuffix(source, []byte("\n")) && !bytes.HasSuffix(result, []byte("\n")) {
+		result = append(result, '\n')
+	}
+
+	// the backup is written only once the transformation succeeded,
+	// but before the file is replaced
+	if backupSuffix != "" {
+		err := writeFileAtomic(fname+backupSuffix, fname, func(w io.Writer) error {
+			_, err := w.Write(source)
+			return err
+		})
+
+		if err != nil {
+			return errors.Wrapf(err, "failed to write a backup")
+		}
+	}
+
+	return writeFileAtomic(fname, fname, func(w io.Writer) error {
+		_, err := w.Write(result)
+		return err
+	})
+}
+
+// writeFileAtomic writes the data into a temporary file in the same
+// directory first and then renames it to the target name, that way
+// readers will never observe a partially written file. Permissions
+// are copied from permsFrom file
+func writeFileAtomic(fname string, permsFrom string, write func(w io.Writer) error) error {
+	stat, err := os.Stat(permsFrom)
+
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(fname), "."+filepath.Base(fname)+".*.tmp")
+
+	if err != nil {
+		return err
+	}
+
+	// it's a noop after a successful rename
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if err := write(tmp); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+
+	if err := tmp.Chmod(stat.Mode().Perm()); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), fname)
+}
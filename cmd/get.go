@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+
+	"github.com/can3p/sackmesser/pkg/cobrahelpers"
+	"github.com/can3p/sackmesser/pkg/operations"
+	"github.com/can3p/sackmesser/pkg/sackmesser"
+	"github.com/can3p/sackmesser/pkg/traverse/types"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// exitCodePathMissing is used to distinguish a missing
+// value from any other failure in scripts
+const exitCodePathMissing = 2
+
+func GetCommand() *cobra.Command {
+	var inputFormat string
+	var outputFormat string
+	var raw bool
+	var docIndices []int
+	var docFilter string
+	var pointerPaths bool
+
+	var getCmd = &cobra.Command{
+		Use:   "get <path> [files...]",
+		Short: "print the value at the path",
+		Long: `Parse incoming object and print the value at the specified path
+
+Input is read from stdin unless files are given. The command exits
+with code 2 if there is no value at the path.
+
+For a multi-document yaml input the value is printed for every selected
+document that has it, documents are separated with ---.`,
+		Args:         cobra.MinimumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if args[0] == "" {
+				return errors.Errorf("Invalid path: the path is empty, e.g. a.b or /a/b is expected")
+			}
+
+			path, err := operations.NewParser(parserOptions(pointerPaths)...).ParsePath(args[0])
+
+			if err != nil {
+				return errors.Wrapf(err, "Invalid path: [%s]", args[0])
+			}
+
+			sel, err := newDocumentSelector(docIndices, docFilter)
+
+			if err != nil {
+				return err
+			}
+
+			if !cmd.Flags().Changed("output-format") {
+				outputFormat = inputFormat
+			}
+
+			sep, err := outputSeparator(outputFormat, args[1:], false)
+
+			if err != nil {
+				return err
+			}
+
+			// raw values are printed one per line
+			if raw {
+				sep = nil
+			}
+
+			transform := bufferedTransform(func(input []byte) ([]byte, error) {
+				return get(input, inputFormat, outputFormat, path, raw, sel)
+			})
+
+			return processFiles(cmd, args[1:], transform, false, "", sep)
+		},
+	}
+
+	getCmd.Flags().Var(cobrahelpers.NewEnumFlag(&inputFormat, "json", sackmesser.Formats...), "input-format", `input format: json, yaml or toml`)
+	getCmd.Flags().Var(cobrahelpers.NewEnumFlag(&outputFormat, "json", sackmesser.Formats...), "output-format", `output format: json, yaml or toml, defaults to the input format`)
+	getCmd.Flags().BoolVarP(&raw, "raw", "r", false, "print scalar values as is, e.g. strings without quotes")
+	addDocumentFlags(getCmd, &docIndices, &docFilter)
+	addPointerFlag(getCmd, &pointerPaths)
+
+	return getCmd
+}
+
+func get(input []byte, inputFormat string, outputFormat string, path types.PathElementSlice, raw bool, sel *documentSelector) ([]byte, error) {
+	docs, err := sackmesser.ParseDocuments(input, inputFormat)
+
+	if err != nil {
+		return nil, err
+	}
+
+	selected, err := sel.selected(docs)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	found := 0
+
+	// documents and wildcard matches without the value are
+	// skipped, it's only an error if none of them has it
+	for _, doc := range selected {
+		nodes, err := findAll(doc, path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, node := range nodes {
+			out, err := formatValue(node, outputFormat, raw)
+
+			if err != nil {
+				return nil, err
+			}
+
+			if found > 0 && outputFormat == "yaml" && !raw {
+				buf.WriteString("---\n")
+			}
+
+			buf.Write(out)
+			found++
+		}
+	}
+
+	if found == 0 {
+		return nil, &exitError{
+			code: exitCodePathMissing,
+			err:  errors.Errorf("no value at path %s", path.String()),
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// findAll returns every node the path points to,
+// missing values are skipped
+func findAll(root types.Node, path types.PathElementSlice) ([]types.Node, error) {
+	paths, err := operations.ExpandPath(root, path)
+
+	if isMissingValue(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	out := []types.Node{}
+
+	for _, p := range paths {
+		node, err := operations.Traverse(root, p)
+
+		if isMissingValue(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		out = append(out, node)
+	}
+
+	return out, nil
+}
+
+// isMissingValue reports whether the path does not point to anything,
+// e.g. an array element JSON Pointer addresses with a negative index
+func isMissingValue(err error) bool {
+	return errors.Is(err, types.ErrFieldMissing) || errors.Is(err, types.ErrIdxOutOfBounds) ||
+		errors.Is(err, types.ErrWrongVisit) || errors.Is(err, types.ErrInvalidIdx)
+}
+
+func formatValue(node types.Node, outputFormat string, raw bool) ([]byte, error) {
+	var out []byte
+	var err error
+
+	if raw && node.NodeType() != types.NodeTypeObject && node.NodeType() != types.NodeTypeArray {
+		out, err = rawScalar(node.Value())
+	} else {
+		out, err = sackmesser.Serialize(node, outputFormat)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.HasSuffix(out, []byte("\n")) {
+		out = append(out, '\n')
+	}
+
+	return out, nil
+}
+
+func rawScalar(v any) ([]byte, error) {
+	switch typed := v.(type) {
+	case string:
+		return []byte(typed), nil
+	case encoding.TextMarshaler:
+		return typed.MarshalText()
+	}
+
+	return json.Marshal(v)
+}
+
+func init() {
+	rootCmd.AddCommand(GetCommand())
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/can3p/sackmesser/pkg/sackmesser"
+	"github.com/can3p/sackmesser/pkg/traverse/simplejson"
+	"github.com/pkg/errors"
+)
+
+const (
+	lineErrorsFail        = "fail"
+	lineErrorsSkip        = "skip"
+	lineErrorsPassthrough = "passthrough"
+)
+
+var lineErrorModes = []string{lineErrorsFail, lineErrorsSkip, lineErrorsPassthrough}
+
+// jsonlTransform treats every line of the input as a separate json document,
+// the input is processed line by line, hence memory consumption does not depend
+// on the input size. Empty lines are dropped. Operations skipped
+// with --continue-on-error are added to the report
+func jsonlTransform(prog *sackmesser.Program, lineErrors string, stderr io.Writer, report *skipReport) transformFunc {
+	return func(in io.Reader, out io.Writer) error {
+		name := inputName(in)
+		reader := bufio.NewReader(in)
+		writer := bufio.NewWriter(out)
+		lineNo := 0
+
+		for {
+			line, readErr := reader.ReadBytes('\n')
+
+			if readErr != nil && readErr != io.EOF {
+				return readErr
+			}
+
+			if len(line) > 0 {
+				lineNo++
+			}
+
+			line = bytes.TrimSpace(line)
+
+			if len(line) > 0 {
+				result, err := modifyRecord(line, prog)
+
+				var skipped *sackmesser.SkippedError
+
+				if errors.As(err, &skipped) {
+					report.addLine(name, lineNo, skipped)
+					err = nil
+				}
+
+				switch {
+				case err == nil:
+					if _, err := writer.Write(append(result, '\n')); err != nil {
+						return err
+					}
+				case lineErrors == lineErrorsSkip:
+					fmt.Fprintf(stderr, "%s: line %d: %s, skipping\n", name, lineNo, err.Error())
+				case lineErrors == lineErrorsPassthrough:
+					fmt.Fprintf(stderr, "%s: line %d: %s, left as is\n", name, lineNo, err.Error())
+
+					if _, err := writer.Write(append(line, '\n')); err != nil {
+						return err
+					}
+				default:
+					if flushErr := writer.Flush(); flushErr != nil {
+						return flushErr
+					}
+
+					return errors.Wrapf(err, "line %d", lineNo)
+				}
+			}
+
+			if readErr == io.EOF {
+				break
+			}
+		}
+
+		return writer.Flush()
+	}
+}
+
+func modifyRecord(line []byte, prog *sackmesser.Program) ([]byte, error) {
+	root, err := simplejson.ParseOrdered(line)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// skipped operations are returned along with the result
+	err = prog.ApplyToNode(root)
+
+	var skipped *sackmesser.SkippedError
+
+	if err != nil && !errors.As(err, &skipped) {
+		return nil, err
+	}
+
+	result, compactErr := simplejson.Compact(root)
+
+	if compactErr != nil {
+		return nil, compactErr
+	}
+
+	return result, err
+}
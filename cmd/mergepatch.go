@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+
+	"github.com/can3p/sackmesser/pkg/operations"
+	"github.com/can3p/sackmesser/pkg/sackmesser"
+	"github.com/can3p/sackmesser/pkg/traverse/types"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func MergePatchCommand() *cobra.Command {
+	var flags fileFlags
+	var docIndices []int
+	var docFilter string
+
+	var mergePatchCmd = &cobra.Command{
+		Use:   "merge-patch <patch-file> [files...]",
+		Short: "apply JSON Merge Patch",
+		Long: `Apply RFC 7396 JSON Merge Patch to the input
+
+Input is read from stdin unless files are given. Objects from the patch
+are merged recursively, null deletes a key and any other value replaces
+the existing one. The patch should be an object since the whole document
+cannot be replaced.
+
+The patch is applied to every document of a multi-document yaml input
+unless documents are picked with --doc or --doc-filter, all the documents
+are written back in the original order.`,
+		Args:         cobra.MinimumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			files := args[1:]
+
+			if err := flags.validateInPlace(cmd, files); err != nil {
+				return err
+			}
+
+			sep, err := outputSeparator(flags.outputFormat, files, flags.inPlace)
+
+			if err != nil {
+				return err
+			}
+
+			sel, err := newDocumentSelector(docIndices, docFilter)
+
+			if err != nil {
+				return err
+			}
+
+			b, err := os.ReadFile(args[0])
+
+			if err != nil {
+				return err
+			}
+
+			dec := json.NewDecoder(bytes.NewReader(b))
+			dec.UseNumber()
+
+			var patch map[string]any
+
+			if err := dec.Decode(&patch); err != nil {
+				return errors.Wrapf(err, "Invalid patch, JSON object expected: %s", args[0])
+			}
+
+			transform := bufferedTransform(func(input []byte) ([]byte, error) {
+				return mergePatch(input, flags.inputFormat, flags.outputFormat, patch, sel)
+			})
+
+			return processFiles(cmd, files, transform, flags.inPlace, flags.backupSuffix, sep)
+		},
+	}
+
+	addFileFlags(mergePatchCmd, &flags)
+	addDocumentFlags(mergePatchCmd, &docIndices, &docFilter)
+
+	return mergePatchCmd
+}
+
+func mergePatch(input []byte, inputFormat string, outputFormat string, patch map[string]any, sel *documentSelector) ([]byte, error) {
+	return sackmesser.Transform(input, inputFormat, outputFormat, sel.indices, sel.predicate, func(_ int, doc types.Node) error {
+		return operations.MergePatch(doc, nil, patch)
+	})
+}
+
+func init() {
+	rootCmd.AddCommand(MergePatchCommand())
+}
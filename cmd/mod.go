@@ -4,101 +4,202 @@ Copyright © 2024 Dmitrii Petrov <dpetroff@gmail.com>
 package cmd
 
 import (
-	"fmt"
 	"io"
 	"os"
+	"strings"
 
 	"github.com/can3p/sackmesser/pkg/cobrahelpers"
 	"github.com/can3p/sackmesser/pkg/operations"
-	"github.com/can3p/sackmesser/pkg/traverse/simplejson"
-	"github.com/can3p/sackmesser/pkg/traverse/simpleyaml"
-	"github.com/can3p/sackmesser/pkg/traverse/types"
+	"github.com/can3p/sackmesser/pkg/sackmesser"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
 func ModCommand() *cobra.Command {
-	var inputFormat string
-	var outputFormat string
+	var flags fileFlags
+	var inputFiles []string
+	var scriptFiles []string
+	var jsonl bool
+	var lineErrors string
+	var docIndices []int
+	var docFilter string
+	var pointerPaths bool
+	var dryRun bool
+	var colorMode string
+	var continueOnError bool
 
 	var modCmd = &cobra.Command{
-		Use:   "mod",
+		Use:   "mod [operations...] [-- files...]",
 		Short: "modify input",
-		Long:  "Parse incoming object and update (or delete) the specified field",
+		Long: `Parse incoming object and update (or delete) the specified field
+
+Input is read from stdin unless files are passed with --input or after
+a double dash. With --in-place every file is rewritten instead of printing
+the result, and the output format defaults to the input format.
+
+Operations can be read from files with -f, one operation per line,
+# starts a comment. Operations from files are applied first.
+
+Every document of a multi-document yaml input is modified unless
+documents are picked with --doc or --doc-filter, all the documents
+are written back in the original order.
+
+With --dry-run (or --diff) nothing is written, a unified diff between
+the original and the modified input serialized in the output format
+is printed instead. The command exits with code 2 if there are changes
+and with code 1 if any of the inputs failed to be processed.
+
+Operations are applied to every document as a whole: if any of them fails,
+the document is left untouched and the command fails. With --continue-on-error
+failing operations are skipped instead and reported once all the input is processed.
+
+Available operations:
+
+` + indent(operations.DefaultRegistry().Help(), "  "),
+		SilenceUsage: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			input, err := io.ReadAll(os.Stdin)
+			opArgs, fileArgs := splitArgs(cmd, args)
+			files := append(inputFiles, fileArgs...)
 
-			if err != nil {
+			if err := flags.validateInPlace(cmd, files); err != nil {
 				return err
 			}
 
-			// here we should dispatch against type to parse,
-			// but we don't do that atm
-			var root types.Node
-			switch inputFormat {
-			case "yaml":
-				root, err = simpleyaml.Parse(input)
-			case "json":
-				root, err = simplejson.Parse(input)
-			default:
-				return fmt.Errorf("Unkonwn input format: %s", inputFormat)
-			}
+			sep, err := outputSeparator(flags.outputFormat, files, flags.inPlace)
 
 			if err != nil {
 				return err
 			}
 
-			if len(args) > 0 {
-				ops := []*operations.OpInstance{}
-				parser := operations.NewParser()
+			docOpts := []sackmesser.Option{
+				sackmesser.WithDocuments(docIndices...),
+				sackmesser.WithDocumentFilter(docFilter),
+			}
 
-				for _, arg := range args {
-					op, err := parser.Parse(arg)
+			opts, err := scriptOptions(scriptFiles)
 
-					if err != nil {
-						return errors.Wrapf(err, "Invalid operation: [%s]", arg)
-					}
+			if err != nil {
+				return err
+			}
 
-					ops = append(ops, op)
-				}
+			if pointerPaths {
+				opts = append(opts, sackmesser.WithPointerPaths())
+			}
 
-				for _, op := range ops {
-					if err := op.Apply(root); err != nil {
-						return errors.Wrapf(err, "failed to apply operation: %s", op.String())
-					}
-				}
+			if continueOnError {
+				opts = append(opts, sackmesser.WithContinueOnError())
 			}
 
-			var outputRoot types.RootNode
+			prog, err := sackmesser.Compile(opArgs, append(opts, docOpts...)...)
 
-			switch outputFormat {
-			case "yaml":
-				outputRoot = simpleyaml.FromNode(root)
-			case "json":
-				outputRoot = simplejson.FromNode(root)
-			default:
-				return fmt.Errorf("Unkonwn ouput format: %s", inputFormat)
+			if err != nil {
+				return err
 			}
 
-			out, err := outputRoot.Serialize()
+			// the input passed through an empty program
+			// is what dry run compares the result with
+			original, err := sackmesser.Compile(nil, docOpts...)
 
 			if err != nil {
 				return err
 			}
 
-			// nolint:forbidigo
-			fmt.Print(string(out))
+			report := &skipReport{}
+			defer report.print(cmd.ErrOrStderr())
+
+			if jsonl {
+				if flags.inputFormat != "json" || flags.outputFormat != "json" {
+					return errors.Errorf("--jsonl works with json input and output only")
+				}
+
+				if len(docIndices) > 0 || docFilter != "" {
+					return errors.Errorf("--doc and --doc-filter do not work together with --jsonl")
+				}
+
+				if dryRun {
+					return dryRunFiles(cmd, files, jsonlTransform(original, lineErrors, io.Discard, &skipReport{}), jsonlTransform(prog, lineErrors, cmd.ErrOrStderr(), report), colorMode)
+				}
+
+				return processFiles(cmd, files, jsonlTransform(prog, lineErrors, cmd.ErrOrStderr(), report), flags.inPlace, flags.backupSuffix, sep)
+			}
+
+			transform := report.transform(prog, flags.inputFormat, flags.outputFormat)
 
-			return nil
+			if dryRun {
+				originalTransform := bufferedTransform(func(input []byte) ([]byte, error) {
+					return original.Apply(input, flags.inputFormat, flags.outputFormat)
+				})
+
+				return dryRunFiles(cmd, files, originalTransform, transform, colorMode)
+			}
+
+			return processFiles(cmd, files, transform, flags.inPlace, flags.backupSuffix, sep)
 		},
 	}
 
-	modCmd.Flags().Var(cobrahelpers.NewEnumFlag(&inputFormat, "json", "json", "yaml"), "input-format", `input format: json or yaml`)
-	modCmd.Flags().Var(cobrahelpers.NewEnumFlag(&outputFormat, "json", "json", "yaml"), "output-format", `input format: json or yaml`)
+	modCmd.Flags().StringArrayVar(&inputFiles, "input", nil, "read input from a file instead of stdin, can be repeated")
+	modCmd.Flags().StringArrayVarP(&scriptFiles, "file", "f", nil, "read operations from a file, can be repeated")
+	modCmd.Flags().BoolVar(&jsonl, "jsonl", false, "treat every line of the input as a separate json document (JSON Lines / NDJSON)")
+	modCmd.Flags().Var(cobrahelpers.NewEnumFlag(&lineErrors, lineErrorsFail, lineErrorModes...), "line-errors", "what to do with lines that failed to be processed in --jsonl mode: fail, skip or passthrough")
+
+	modCmd.Flags().BoolVar(&dryRun, "dry-run", false, "print a unified diff of the changes instead of the result, exit with code 2 if there are any")
+	modCmd.Flags().BoolVar(&dryRun, "diff", false, "same as --dry-run")
+	modCmd.Flags().Var(cobrahelpers.NewEnumFlag(&colorMode, colorAuto, colorModes...), "color", "colorize the diff: auto, always or never")
+	modCmd.Flags().BoolVar(&continueOnError, "continue-on-error", false, "skip operations that fail instead of leaving the document untouched, skipped operations are reported at the end")
+
+	addFileFlags(modCmd, &flags)
+	addDocumentFlags(modCmd, &docIndices, &docFilter)
+	addPointerFlag(modCmd, &pointerPaths)
 
 	return modCmd
 } // modCmd represents the mod command
 
+// everything after a double dash is treated as a list of files,
+// everything before - as a list of operations
+func splitArgs(cmd *cobra.Command, args []string) ([]string, []string) {
+	dashIdx := cmd.ArgsLenAtDash()
+
+	if dashIdx < 0 {
+		return args, nil
+	}
+
+	return args[:dashIdx], args[dashIdx:]
+}
+
+func indent(s string, prefix string) string {
+	lines := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+
+	return prefix + strings.Join(lines, "\n"+prefix)
+}
+
+func scriptOptions(files []string) ([]sackmesser.Option, error) {
+	opts := []sackmesser.Option{}
+
+	for _, fname := range files {
+		b, err := os.ReadFile(fname)
+
+		if err != nil {
+			return nil, err
+		}
+
+		opts = append(opts, sackmesser.WithScript(fname, string(b)))
+	}
+
+	return opts, nil
+}
+
+func addPointerFlag(cmd *cobra.Command, pointerPaths *bool) {
+	cmd.Flags().BoolVar(pointerPaths, "json-pointer", false, `treat quoted paths that start with a slash as JSON Pointers, e.g. "/a b/c". Unquoted pointers are always recognized`)
+}
+
+func parserOptions(pointerPaths bool) []operations.ParserOption {
+	if pointerPaths {
+		return []operations.ParserOption{operations.WithPointerPaths()}
+	}
+
+	return nil
+}
+
 func init() {
 	rootCmd.AddCommand(ModCommand())
 }
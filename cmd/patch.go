@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/can3p/sackmesser/pkg/jsonpatch"
+	"github.com/can3p/sackmesser/pkg/operations"
+	"github.com/can3p/sackmesser/pkg/sackmesser"
+	"github.com/can3p/sackmesser/pkg/traverse/types"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func PatchCommand() *cobra.Command {
+	var flags fileFlags
+	var docIndices []int
+	var docFilter string
+
+	var patchCmd = &cobra.Command{
+		Use:   "patch <patch-file> [files...]",
+		Short: "apply JSON Patch",
+		Long: `Apply RFC 6902 JSON Patch to the input
+
+Input is read from stdin unless files are given. The patch is applied as
+a whole: if any of the operations fails, e.g. a test operation does not hold,
+nothing is changed and the command fails.
+
+The patch is applied to every document of a multi-document yaml input
+unless documents are picked with --doc or --doc-filter, all the documents
+are written back in the original order.
+
+Replacing the whole document is not supported, add and replace
+operations with an empty path are rejected.`,
+		Args:         cobra.MinimumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			files := args[1:]
+
+			if err := flags.validateInPlace(cmd, files); err != nil {
+				return err
+			}
+
+			sep, err := outputSeparator(flags.outputFormat, files, flags.inPlace)
+
+			if err != nil {
+				return err
+			}
+
+			sel, err := newDocumentSelector(docIndices, docFilter)
+
+			if err != nil {
+				return err
+			}
+
+			b, err := os.ReadFile(args[0])
+
+			if err != nil {
+				return err
+			}
+
+			ops, err := jsonpatch.Parse(b)
+
+			if err != nil {
+				return errors.Wrapf(err, "Invalid patch: %s", args[0])
+			}
+
+			transform := bufferedTransform(func(input []byte) ([]byte, error) {
+				return patch(input, flags.inputFormat, flags.outputFormat, ops, sel)
+			})
+
+			return processFiles(cmd, files, transform, flags.inPlace, flags.backupSuffix, sep)
+		},
+	}
+
+	addFileFlags(patchCmd, &flags)
+	addDocumentFlags(patchCmd, &docIndices, &docFilter)
+
+	return patchCmd
+}
+
+func patch(input []byte, inputFormat string, outputFormat string, ops []*operations.OpInstance, sel *documentSelector) ([]byte, error) {
+	return sackmesser.Transform(input, inputFormat, outputFormat, sel.indices, sel.predicate, func(_ int, doc types.Node) error {
+		return jsonpatch.Apply(doc, ops)
+	})
+}
+
+func init() {
+	rootCmd.AddCommand(PatchCommand())
+}
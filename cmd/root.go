@@ -6,6 +6,7 @@ import (
 	cmd "github.com/can3p/kleiner/shared/cmd/cobra"
 	"github.com/can3p/kleiner/shared/published"
 	"github.com/can3p/sackmesser/generated/buildinfo"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
@@ -22,11 +23,32 @@ var rootCmd = &cobra.Command{
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
 	err := rootCmd.Execute()
+
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		os.Exit(exitErr.code)
+	}
+
 	if err != nil {
 		os.Exit(1)
 	}
 }
 
+// exitError makes the command exit with a specific code
+// instead of the default one
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
 func init() {
 	info := buildinfo.Info()
 
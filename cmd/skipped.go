@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/can3p/sackmesser/pkg/sackmesser"
+	"github.com/pkg/errors"
+)
+
+// skipReport collects operations skipped with --continue-on-error,
+// they are printed once all the inputs have been processed
+type skipReport struct {
+	lines []string
+}
+
+func (r *skipReport) addDocuments(name string, skipped *sackmesser.SkippedError) {
+	for _, f := range skipped.Failures {
+		r.lines = append(r.lines, fmt.Sprintf("%s: document %d: %s", name, f.Doc, f.OpError))
+	}
+}
+
+func (r *skipReport) addLine(name string, lineNo int, skipped *sackmesser.SkippedError) {
+	for _, f := range skipped.Failures {
+		r.lines = append(r.lines, fmt.Sprintf("%s: line %d: %s", name, lineNo, f.OpError))
+	}
+}
+
+func (r *skipReport) print(w io.Writer) {
+	if len(r.lines) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "skipped %d operation(s):\n", len(r.lines))
+
+	for _, line := range r.lines {
+		fmt.Fprintf(w, "  %s\n", line)
+	}
+}
+
+// transform applies the program to the whole input,
+// skipped operations do not fail the transformation
+func (r *skipReport) transform(prog *sackmesser.Program, inputFormat string, outputFormat string) transformFunc {
+	return func(in io.Reader, out io.Writer) error {
+		name := inputName(in)
+
+		return bufferedTransform(func(input []byte) ([]byte, error) {
+			result, err := prog.Apply(input, inputFormat, outputFormat)
+
+			var skipped *sackmesser.SkippedError
+
+			if errors.As(err, &skipped) {
+				r.addDocuments(name, skipped)
+				return result, nil
+			}
+
+			return result, err
+		})(in, out)
+	}
+}
+
+// namedReader lets transformations know where the input comes from
+type namedReader struct {
+	io.Reader
+	name string
+}
+
+func (r *namedReader) Name() string {
+	return r.name
+}
+
+func inputName(in io.Reader) string {
+	if in == os.Stdin {
+		return "stdin"
+	}
+
+	if named, ok := in.(interface{ Name() string }); ok {
+		return named.Name()
+	}
+
+	return "stdin"
+}
@@ -0,0 +1,76 @@
+package diff
+
+import (
+	"github.com/can3p/sackmesser/pkg/jsonpatch"
+	"github.com/can3p/sackmesser/pkg/operations"
+	"github.com/can3p/sackmesser/pkg/traverse/types"
+	"github.com/pkg/errors"
+)
+
+type changeKind int
+
+const (
+	// value at the path is replaced
+	changeReplace changeKind = iota
+	// value at the path is removed
+	changeRemove
+	// object field is added
+	changeAdd
+	// array element is inserted before the element at the path
+	changeInsert
+	// array element is appended to the array at the path
+	changeAppend
+	// array element is moved from one index to another
+	changeMove
+)
+
+type change struct {
+	kind  changeKind
+	path  types.PathElementSlice
+	from  types.PathElementSlice
+	value any
+}
+
+func (ch *change) operation() (*operations.OpInstance, error) {
+	switch ch.kind {
+	case changeReplace, changeAdd:
+		if len(ch.path) == 0 {
+			return nil, errors.Errorf("documents differ at the top level, the whole document cannot be replaced")
+		}
+
+		return &operations.OpInstance{Op: operations.Set, Name: "set", Path: ch.path, Args: []any{ch.value}}, nil
+	case changeRemove:
+		return &operations.OpInstance{Op: operations.Delete, Name: "del", Path: ch.path, Args: []any{}}, nil
+	case changeAppend:
+		if len(ch.path) == 0 {
+			return nil, errors.Errorf("elements cannot be pushed to the top level array")
+		}
+
+		return &operations.OpInstance{Op: operations.Push, Name: "push", Path: ch.path, Args: []any{ch.value}}, nil
+	case changeInsert:
+		return nil, errors.Errorf("element cannot be inserted at %s with operations, use JSON Patch", ch.path.Pointer())
+	}
+
+	return nil, errors.Errorf("element cannot be moved to %s with operations, use JSON Patch", ch.path.Pointer())
+}
+
+// patchOperation fails for the whole document replacement, since
+// jsonpatch package cannot apply it
+func (ch *change) patchOperation() (*jsonpatch.Operation, error) {
+	switch ch.kind {
+	case changeReplace:
+		if len(ch.path) == 0 {
+			return nil, errors.Errorf("documents differ at the top level, the whole document cannot be replaced")
+		}
+
+		return &jsonpatch.Operation{Op: "replace", Path: ch.path.Pointer(), Value: ch.value}, nil
+	case changeRemove:
+		return &jsonpatch.Operation{Op: "remove", Path: ch.path.Pointer()}, nil
+	case changeAdd, changeInsert:
+		return &jsonpatch.Operation{Op: "add", Path: ch.path.Pointer(), Value: ch.value}, nil
+	case changeAppend:
+		return &jsonpatch.Operation{Op: "add", Path: ch.path.Pointer() + "/-", Value: ch.value}, nil
+	}
+
+	return &jsonpatch.Operation{Op: "move", From: ch.from.Pointer(), Path: ch.path.Pointer()}, nil
+}
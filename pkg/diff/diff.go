@@ -0,0 +1,486 @@
+// Package diff finds the difference between two documents and
+// expresses it either as a list of operations that turn one into
+// the other or as RFC 6902 JSON Patch.
+package diff
+
+import (
+	"github.com/can3p/sackmesser/pkg/jsonpatch"
+	"github.com/can3p/sackmesser/pkg/operations"
+	"github.com/can3p/sackmesser/pkg/traverse/types"
+	"github.com/pkg/errors"
+)
+
+// ArrayStrategy defines how elements of two arrays are matched
+type ArrayStrategy string
+
+var (
+	// ArraysByIndex compares elements with the same index,
+	// extra elements are removed or appended
+	ArraysByIndex ArrayStrategy = "index"
+	// ArraysLCS keeps the longest common subsequence of elements
+	// in place and inserts or removes the rest
+	ArraysLCS ArrayStrategy = "lcs"
+	// ArraysByKey matches objects by the value of the key field,
+	// elements can be moved. Arrays with elements without the key
+	// or with duplicate keys are compared by index
+	ArraysByKey ArrayStrategy = "key"
+)
+
+var ArrayStrategies = []string{string(ArraysByIndex), string(ArraysLCS), string(ArraysByKey)}
+
+type config struct {
+	arrays ArrayStrategy
+	key    string
+}
+
+type Option func(c *config)
+
+// WithArrayStrategy sets the way arrays are compared, ArraysByIndex is the default
+func WithArrayStrategy(strategy ArrayStrategy) Option {
+	return func(c *config) {
+		c.arrays = strategy
+	}
+}
+
+// WithArrayKey matches array elements by the value of the field
+func WithArrayKey(field string) Option {
+	return func(c *config) {
+		c.arrays = ArraysByKey
+		c.key = field
+	}
+}
+
+func newConfig(opts []Option) (*config, error) {
+	c := &config{arrays: ArraysByIndex}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	switch c.arrays {
+	case ArraysByIndex, ArraysLCS:
+	case ArraysByKey:
+		if c.key == "" {
+			return nil, errors.Errorf("key field is required to compare arrays by key")
+		}
+	default:
+		return nil, errors.Errorf("unknown array strategy [%s]", c.arrays)
+	}
+
+	return c, nil
+}
+
+// Operations returns set, del and push operations that turn from into to.
+// Objects are compared key by key, a value of a different type is replaced as a whole.
+// Operations cannot replace the whole document, insert elements in the middle of
+// an array or move them, JSON Patch should be used for such differences
+func Operations(from types.Node, to types.Node, opts ...Option) ([]*operations.OpInstance, error) {
+	changes, err := diff(from, to, opts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]*operations.OpInstance, 0, len(changes))
+
+	for _, ch := range changes {
+		op, err := ch.operation()
+
+		if err != nil {
+			return nil, err
+		}
+
+		out = append(out, op)
+	}
+
+	return out, nil
+}
+
+// Patch returns JSON Patch that turns from into to. The whole document
+// cannot be replaced, the same way as with Operations
+func Patch(from types.Node, to types.Node, opts ...Option) ([]*jsonpatch.Operation, error) {
+	changes, err := diff(from, to, opts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]*jsonpatch.Operation, 0, len(changes))
+
+	for _, ch := range changes {
+		op, err := ch.patchOperation()
+
+		if err != nil {
+			return nil, err
+		}
+
+		out = append(out, op)
+	}
+
+	return out, nil
+}
+
+func diff(from types.Node, to types.Node, opts []Option) ([]*change, error) {
+	c, err := newConfig(opts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	d := &differ{config: c, changes: []*change{}}
+
+	if err := d.nodes(from, to, types.PathElementSlice{}); err != nil {
+		return nil, err
+	}
+
+	return d.changes, nil
+}
+
+type differ struct {
+	*config
+	changes []*change
+}
+
+func (d *differ) add(ch *change) {
+	d.changes = append(d.changes, ch)
+}
+
+func (d *differ) nodes(from types.Node, to types.Node, path types.PathElementSlice) error {
+	if !isContainer(from) || from.NodeType() != to.NodeType() {
+		if !types.Equal(from.Value(), to.Value()) {
+			d.add(&change{kind: changeReplace, path: path, value: to.Value()})
+		}
+
+		return nil
+	}
+
+	if from.NodeType() == types.NodeTypeObject {
+		return d.objects(from, to, path)
+	}
+
+	switch d.arrays {
+	case ArraysLCS:
+		return d.arraysLCS(from, to, path)
+	case ArraysByKey:
+		return d.arraysByKey(from, to, path)
+	}
+
+	return d.arraysByIndex(from, to, path)
+}
+
+func (d *differ) objects(from types.Node, to types.Node, path types.PathElementSlice) error {
+	toFields := map[string]bool{}
+
+	for _, field := range to.Fields() {
+		toFields[field.ObjectField] = true
+	}
+
+	fromFields := map[string]bool{}
+
+	for _, field := range from.Fields() {
+		fromFields[field.ObjectField] = true
+
+		fieldPath := appendPath(path, field)
+
+		if !toFields[field.ObjectField] {
+			d.add(&change{kind: changeRemove, path: fieldPath})
+			continue
+		}
+
+		if err := d.children(from, field, to, field, fieldPath); err != nil {
+			return err
+		}
+	}
+
+	for _, field := range to.Fields() {
+		if fromFields[field.ObjectField] {
+			continue
+		}
+
+		value, err := to.GetField(field)
+
+		if err != nil {
+			return err
+		}
+
+		d.add(&change{kind: changeAdd, path: appendPath(path, field), value: value})
+	}
+
+	return nil
+}
+
+func (d *differ) arraysByIndex(from types.Node, to types.Node, path types.PathElementSlice) error {
+	a := &arrayEditor{differ: d, from: from, to: to, path: path, length: len(from.Fields())}
+
+	return a.gap(0, a.length, 0, len(to.Fields()))
+}
+
+// elements that are not a part of the longest common subsequence
+// are compared by index if both arrays have them at the same place
+func (d *differ) arraysLCS(from types.Node, to types.Node, path types.PathElementSlice) error {
+	fromItems := from.Value().([]any)
+	toItems := to.Value().([]any)
+
+	a := &arrayEditor{differ: d, from: from, to: to, path: path, length: len(fromItems)}
+	fromIdx, toIdx := 0, 0
+
+	for _, pair := range lcs(fromItems, toItems) {
+		if err := a.gap(fromIdx, pair[0], toIdx, pair[1]); err != nil {
+			return err
+		}
+
+		// the element is the same, it only needs to be skipped
+		a.idx++
+		fromIdx, toIdx = pair[0]+1, pair[1]+1
+	}
+
+	return a.gap(fromIdx, len(fromItems), toIdx, len(toItems))
+}
+
+// lcs returns pairs of indices of equal elements that form
+// the longest common subsequence of two arrays
+func lcs(a []any, b []any) [][2]int {
+	lengths := make([][]int, len(a)+1)
+
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case types.Equal(a[i], b[j]):
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			case lengths[i+1][j] >= lengths[i][j+1]:
+				lengths[i][j] = lengths[i+1][j]
+			default:
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+
+	out := [][2]int{}
+
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case types.Equal(a[i], b[j]):
+			out = append(out, [2]int{i, j})
+			i++
+			j++
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+
+	return out
+}
+
+func (d *differ) arraysByKey(from types.Node, to types.Node, path types.PathElementSlice) error {
+	fromItems := from.Value().([]any)
+	toItems := to.Value().([]any)
+
+	fromKeys, ok := d.keys(fromItems)
+
+	if !ok {
+		return d.arraysByIndex(from, to, path)
+	}
+
+	toKeys, ok := d.keys(toItems)
+
+	if !ok {
+		return d.arraysByIndex(from, to, path)
+	}
+
+	fromPositions := map[string]int{}
+
+	for idx, key := range fromKeys {
+		fromPositions[key] = idx
+	}
+
+	toPositions := map[string]int{}
+
+	for idx, key := range toKeys {
+		toPositions[key] = idx
+	}
+
+	// current keeps keys of the elements in the order they
+	// have in the array after the changes made so far
+	current := []string{}
+
+	for idx := len(fromKeys) - 1; idx >= 0; idx-- {
+		if _, ok := toPositions[fromKeys[idx]]; !ok {
+			d.add(&change{kind: changeRemove, path: appendPath(path, types.PathElement{ArrayIdx: idx})})
+		}
+	}
+
+	for _, key := range fromKeys {
+		if _, ok := toPositions[key]; ok {
+			current = append(current, key)
+		}
+	}
+
+	for idx, key := range toKeys {
+		elPath := appendPath(path, types.PathElement{ArrayIdx: idx})
+
+		fromIdx, existed := fromPositions[key]
+
+		if !existed {
+			ch := &change{kind: changeInsert, path: elPath, value: toItems[idx]}
+
+			if idx == len(current) {
+				ch = &change{kind: changeAppend, path: path, value: toItems[idx]}
+			}
+
+			d.add(ch)
+			current = insertKey(current, idx, key)
+			continue
+		}
+
+		currentIdx := indexOfKey(current, key)
+
+		if currentIdx != idx {
+			d.add(&change{kind: changeMove, path: elPath, from: appendPath(path, types.PathElement{ArrayIdx: currentIdx})})
+			current = insertKey(append(current[:currentIdx:currentIdx], current[currentIdx+1:]...), idx, key)
+		}
+
+		fromField := types.PathElement{ArrayIdx: fromIdx}
+		toField := types.PathElement{ArrayIdx: idx}
+
+		if err := d.children(from, fromField, to, toField, elPath); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// keys returns serialized values of the key field of every
+// element, elements should be objects with unique keys
+func (d *differ) keys(items []any) ([]string, bool) {
+	out := make([]string, 0, len(items))
+	seen := map[string]bool{}
+
+	for _, item := range items {
+		typed, ok := item.(map[string]any)
+
+		if !ok {
+			return nil, false
+		}
+
+		value, ok := typed[d.key]
+
+		if !ok {
+			return nil, false
+		}
+
+		key := operations.FormatValue(value)
+
+		if seen[key] {
+			return nil, false
+		}
+
+		seen[key] = true
+		out = append(out, key)
+	}
+
+	return out, true
+}
+
+func indexOfKey(keys []string, key string) int {
+	for idx, k := range keys {
+		if k == key {
+			return idx
+		}
+	}
+
+	return -1
+}
+
+func insertKey(keys []string, idx int, key string) []string {
+	out := make([]string, 0, len(keys)+1)
+	out = append(out, keys[:idx]...)
+	out = append(out, key)
+
+	return append(out, keys[idx:]...)
+}
+
+func (d *differ) children(from types.Node, fromField types.PathElement, to types.Node, toField types.PathElement, path types.PathElementSlice) error {
+	fromChild, err := from.Visit(fromField)
+
+	if err != nil {
+		return err
+	}
+
+	toChild, err := to.Visit(toField)
+
+	if err != nil {
+		return err
+	}
+
+	return d.nodes(fromChild, toChild, path)
+}
+
+// arrayEditor tracks the index of the element that is being
+// changed and the length of the array as changes are made
+type arrayEditor struct {
+	*differ
+	from   types.Node
+	to     types.Node
+	path   types.PathElementSlice
+	idx    int
+	length int
+}
+
+// gap turns from[fromStart:fromEnd] into to[toStart:toEnd], elements are compared
+// by index, extra elements are either removed starting from the end or inserted
+func (a *arrayEditor) gap(fromStart, fromEnd, toStart, toEnd int) error {
+	common := min(fromEnd-fromStart, toEnd-toStart)
+
+	for offset := 0; offset < common; offset++ {
+		fromField := types.PathElement{ArrayIdx: fromStart + offset}
+		toField := types.PathElement{ArrayIdx: toStart + offset}
+
+		if err := a.children(a.from, fromField, a.to, toField, appendPath(a.path, types.PathElement{ArrayIdx: a.idx})); err != nil {
+			return err
+		}
+
+		a.idx++
+	}
+
+	for offset := fromEnd - fromStart - 1; offset >= common; offset-- {
+		a.add(&change{kind: changeRemove, path: appendPath(a.path, types.PathElement{ArrayIdx: a.idx + offset - common})})
+		a.length--
+	}
+
+	for offset := common; offset < toEnd-toStart; offset++ {
+		value, err := a.to.GetField(types.PathElement{ArrayIdx: toStart + offset})
+
+		if err != nil {
+			return err
+		}
+
+		if a.idx == a.length {
+			a.add(&change{kind: changeAppend, path: a.path, value: value})
+		} else {
+			a.add(&change{kind: changeInsert, path: appendPath(a.path, types.PathElement{ArrayIdx: a.idx}), value: value})
+		}
+
+		a.idx++
+		a.length++
+	}
+
+	return nil
+}
+
+func isContainer(node types.Node) bool {
+	return node.NodeType() == types.NodeTypeObject || node.NodeType() == types.NodeTypeArray
+}
+
+func appendPath(path types.PathElementSlice, el types.PathElement) types.PathElementSlice {
+	out := make(types.PathElementSlice, 0, len(path)+1)
+	out = append(out, path...)
+
+	return append(out, el)
+}
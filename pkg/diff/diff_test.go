@@ -0,0 +1,287 @@
+package diff
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/can3p/sackmesser/pkg/jsonpatch"
+	"github.com/can3p/sackmesser/pkg/operations"
+	"github.com/can3p/sackmesser/pkg/traverse/simplejson"
+	"github.com/can3p/sackmesser/pkg/traverse/types"
+)
+
+func TestOperations(t *testing.T) {
+	examples := []struct {
+		description string
+		from        string
+		to          string
+		opts        []Option
+		expected    []string
+		isErr       bool
+	}{
+		{
+			description: "equal documents",
+			from:        `{ "a": 1, "b": [ 1, 2 ] }`,
+			to:          `{ "b": [ 1.0, 2 ], "a": 1 }`,
+			expected:    []string{},
+		},
+		{
+			description: "changed, deleted and added fields",
+			from:        `{ "a": 1, "b": { "c": true, "d": "x" } }`,
+			to:          `{ "a": 2, "b": { "c": true }, "e": { "f": null } }`,
+			expected: []string{
+				`set(a, 2)`,
+				`del(b.d)`,
+				`set(e, {"f":null})`,
+			},
+		},
+		{
+			description: "value of a different type is replaced",
+			from:        `{ "a": { "b": 1 } }`,
+			to:          `{ "a": [ 1 ] }`,
+			expected:    []string{`set(a, [1])`},
+		},
+		{
+			description: "array grows",
+			from:        `{ "a": [ 1, 2 ] }`,
+			to:          `{ "a": [ 1, 3, { "b": 4 } ] }`,
+			expected: []string{
+				`set(a[1], 3)`,
+				`push(a, {"b":4})`,
+			},
+		},
+		{
+			description: "array shrinks",
+			from:        `{ "a": [ 1, 2, 3, 4 ] }`,
+			to:          `{ "a": [ 0, 2 ] }`,
+			expected: []string{
+				`set(a[0], 0)`,
+				`del(a[3])`,
+				`del(a[2])`,
+			},
+		},
+		{
+			description: "fields that are not identifiers are quoted",
+			from:        `{ "a b": { "true": 1, "0": "x" } }`,
+			to:          `{ "a b": { "true": 2, "0": "say \"hi\"\n" } }`,
+			expected: []string{
+				`set("a b"."0", "say \"hi\"\n")`,
+				`set("a b"."true", 2)`,
+			},
+		},
+		{
+			description: "empty keys",
+			from:        `{ "": { "": 1 } }`,
+			to:          `{ "": { "": 2, "a": 1 } }`,
+			expected: []string{
+				`set(""."", 2)`,
+				`set("".a, 1)`,
+			},
+		},
+		{
+			description: "top level array",
+			from:        `[ 1, 2 ]`,
+			to:          `[ 3 ]`,
+			expected: []string{
+				`set([0], 3)`,
+				`del([1])`,
+			},
+		},
+		{
+			description: "top level array cannot grow",
+			from:        `[ 1 ]`,
+			to:          `[ 1, 2 ]`,
+			isErr:       true,
+		},
+		{
+			description: "lcs can remove and append elements",
+			from:        `{ "a": [ 1, 2, 3 ] }`,
+			to:          `{ "a": [ 1, 3, 4 ] }`,
+			opts:        []Option{WithArrayStrategy(ArraysLCS)},
+			expected: []string{
+				`del(a[1])`,
+				`push(a, 4)`,
+			},
+		},
+		{
+			description: "elements cannot be inserted with operations",
+			from:        `{ "a": [ 1, 2 ] }`,
+			to:          `{ "a": [ 0, 1, 2 ] }`,
+			opts:        []Option{WithArrayStrategy(ArraysLCS)},
+			isErr:       true,
+		},
+		{
+			description: "top level values of different types",
+			from:        `{ "a": 1 }`,
+			to:          `[ 1 ]`,
+			isErr:       true,
+		},
+	}
+
+	parser := operations.NewParser()
+
+	for idx, ex := range examples {
+		from := simplejson.MustParse([]byte(ex.from))
+		to := simplejson.MustParse([]byte(ex.to))
+
+		ops, err := Operations(from, to, ex.opts...)
+
+		if ex.isErr {
+			assert.Error(t, err, "[Ex %d - %s]", idx+1, ex.description)
+			continue
+		} else {
+			assert.NoError(t, err, "[Ex %d - %s]", idx+1, ex.description)
+		}
+
+		expressions := []string{}
+
+		for _, op := range ops {
+			expressions = append(expressions, op.Expression())
+		}
+
+		assert.Equal(t, ex.expected, expressions, "[Ex %d - %s]", idx+1, ex.description)
+
+		// the output should be replayable
+		for _, expr := range expressions {
+			op, err := parser.Parse(expr)
+			assert.NoError(t, err, "[Ex %d - %s] %s", idx+1, ex.description, expr)
+			assert.NoError(t, op.Apply(from), "[Ex %d - %s] %s", idx+1, ex.description, expr)
+		}
+
+		assert.True(t, types.Equal(to.Value(), from.Value()), "[Ex %d - %s]", idx+1, ex.description)
+	}
+}
+
+func TestPatch(t *testing.T) {
+	examples := []struct {
+		description string
+		from        string
+		to          string
+		opts        []Option
+		expected    string
+		isErr       bool
+	}{
+		{
+			description: "objects",
+			from:        `{ "a": 1, "b": { "c": true, "d": "x" } }`,
+			to:          `{ "a": null, "b": { "c": true }, "e": [ 1 ] }`,
+			expected: `[
+				{ "op": "replace", "path": "/a", "value": null },
+				{ "op": "remove", "path": "/b/d" },
+				{ "op": "add", "path": "/e", "value": [ 1 ] }
+			]`,
+		},
+		{
+			description: "keys are escaped",
+			from:        `{ "a/b": 1, "c~d": 1 }`,
+			to:          `{ "a/b": 2 }`,
+			expected: `[
+				{ "op": "replace", "path": "/a~1b", "value": 2 },
+				{ "op": "remove", "path": "/c~0d" }
+			]`,
+		},
+		{
+			description: "arrays by index",
+			from:        `{ "a": [ 1, 2, 3 ], "b": [ 1 ] }`,
+			to:          `{ "a": [ 0, 1 ], "b": [ 1, 2 ] }`,
+			expected: `[
+				{ "op": "replace", "path": "/a/0", "value": 0 },
+				{ "op": "replace", "path": "/a/1", "value": 1 },
+				{ "op": "remove", "path": "/a/2" },
+				{ "op": "add", "path": "/b/-", "value": 2 }
+			]`,
+		},
+		{
+			description: "arrays by lcs",
+			from:        `{ "a": [ 1, 2, 3, 4 ] }`,
+			to:          `{ "a": [ 0, 1, 3, 5, 4, 6 ] }`,
+			opts:        []Option{WithArrayStrategy(ArraysLCS)},
+			expected: `[
+				{ "op": "add", "path": "/a/0", "value": 0 },
+				{ "op": "remove", "path": "/a/2" },
+				{ "op": "add", "path": "/a/3", "value": 5 },
+				{ "op": "add", "path": "/a/-", "value": 6 }
+			]`,
+		},
+		{
+			description: "lcs compares elements at the same place",
+			from:        `{ "a": [ 1, { "b": 1, "c": 2 }, 3 ] }`,
+			to:          `{ "a": [ 1, { "b": 1, "c": 3 }, 3 ] }`,
+			opts:        []Option{WithArrayStrategy(ArraysLCS)},
+			expected: `[
+				{ "op": "replace", "path": "/a/1/c", "value": 3 }
+			]`,
+		},
+		{
+			description: "arrays by key",
+			from:        `{ "a": [ { "id": 1, "v": 1 }, { "id": 2, "v": 2 }, { "id": 3, "v": 3 } ] }`,
+			to:          `{ "a": [ { "id": 3, "v": 3 }, { "id": 4 }, { "id": 1, "v": 0 } ] }`,
+			opts:        []Option{WithArrayKey("id")},
+			expected: `[
+				{ "op": "remove", "path": "/a/1" },
+				{ "op": "move", "from": "/a/1", "path": "/a/0" },
+				{ "op": "add", "path": "/a/1", "value": { "id": 4 } },
+				{ "op": "replace", "path": "/a/2/v", "value": 0 }
+			]`,
+		},
+		{
+			description: "arrays without keys are compared by index",
+			from:        `{ "a": [ { "id": 1 }, 2 ] }`,
+			to:          `{ "a": [ 2, { "id": 1 } ] }`,
+			opts:        []Option{WithArrayKey("id")},
+			expected: `[
+				{ "op": "replace", "path": "/a/0", "value": 2 },
+				{ "op": "replace", "path": "/a/1", "value": { "id": 1 } }
+			]`,
+		},
+		{
+			description: "whole document cannot be replaced",
+			from:        `{ "a": 1 }`,
+			to:          `[ 1 ]`,
+			isErr:       true,
+		},
+		{
+			description: "top level array",
+			from:        `[ 1 ]`,
+			to:          `[ 2, 3 ]`,
+			expected: `[
+				{ "op": "replace", "path": "/0", "value": 2 },
+				{ "op": "add", "path": "/-", "value": 3 }
+			]`,
+		},
+		{
+			description: "key strategy requires a key",
+			from:        `{ "a": 1 }`,
+			to:          `{ "a": 1 }`,
+			opts:        []Option{WithArrayStrategy(ArraysByKey)},
+			isErr:       true,
+		},
+	}
+
+	for idx, ex := range examples {
+		from := simplejson.MustParse([]byte(ex.from))
+		to := simplejson.MustParse([]byte(ex.to))
+
+		patch, err := Patch(from, to, ex.opts...)
+
+		if ex.isErr {
+			assert.Error(t, err, "[Ex %d - %s]", idx+1, ex.description)
+			continue
+		} else {
+			assert.NoError(t, err, "[Ex %d - %s]", idx+1, ex.description)
+		}
+
+		b, err := json.Marshal(patch)
+		assert.NoError(t, err, "[Ex %d - %s]", idx+1, ex.description)
+
+		expected := simplejson.MustParse([]byte(ex.expected))
+		assert.Equal(t, expected.Value(), simplejson.MustParse(b).Value(), "[Ex %d - %s]", idx+1, ex.description)
+
+		ops, err := jsonpatch.Parse(b)
+		assert.NoError(t, err, "[Ex %d - %s]", idx+1, ex.description)
+		assert.NoError(t, jsonpatch.Apply(from, ops), "[Ex %d - %s]", idx+1, ex.description)
+
+		assert.True(t, types.Equal(to.Value(), from.Value()), "[Ex %d - %s]", idx+1, ex.description)
+	}
+}
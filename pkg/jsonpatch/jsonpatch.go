@@ -0,0 +1,269 @@
+// Package jsonpatch applies RFC 6902 JSON Patch documents to types.Node.
+//
+// Every patch operation is converted into an operations.OpInstance, paths are
+// JSON Pointers. The patch is applied as a whole: if any of the operations
+// fails, e.g. because a test operation did not hold, the document is left untouched.
+//
+// The only deviation from the RFC is that the whole document cannot be
+// replaced, i.e. add and replace operations with an empty path are rejected.
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/can3p/sackmesser/pkg/operations"
+	"github.com/can3p/sackmesser/pkg/traverse/types"
+	"github.com/pkg/errors"
+)
+
+var ErrTestFailed = errors.New("test operation failed")
+
+var patchOperations = map[string]operations.Operation{
+	"add":     Add,
+	"remove":  Remove,
+	"replace": Replace,
+	"move":    Move,
+	"copy":    Copy,
+	"test":    Test,
+}
+
+// Operation is a single operation of JSON Patch
+type Operation struct {
+	Op    string
+	Path  string
+	From  string
+	Value any
+}
+
+// MarshalJSON only writes the fields the operation has,
+// value is written even if it's null
+func (op *Operation) MarshalJSON() ([]byte, error) {
+	var out any
+
+	switch op.Op {
+	case "add", "replace", "test":
+		out = struct {
+			Op    string `json:"op"`
+			Path  string `json:"path"`
+			Value any    `json:"value"`
+		}{op.Op, op.Path, op.Value}
+	case "move", "copy":
+		out = struct {
+			Op   string `json:"op"`
+			From string `json:"from"`
+			Path string `json:"path"`
+		}{op.Op, op.From, op.Path}
+	default:
+		out = struct {
+			Op   string `json:"op"`
+			Path string `json:"path"`
+		}{op.Op, op.Path}
+	}
+
+	var buf bytes.Buffer
+
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+
+	if err := enc.Encode(out); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSpace(buf.Bytes()), nil
+}
+
+// Parse converts a JSON Patch document into a list of operations,
+// from field of move and copy operations is passed as the first argument
+func Parse(b []byte) ([]*operations.OpInstance, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	var patch []map[string]any
+
+	if err := dec.Decode(&patch); err != nil {
+		return nil, errors.Wrapf(err, "JSON Patch should be an array of operations")
+	}
+
+	out := make([]*operations.OpInstance, 0, len(patch))
+
+	for idx, raw := range patch {
+		op, err := parseOperation(raw)
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "operation %d", idx)
+		}
+
+		out = append(out, op)
+	}
+
+	return out, nil
+}
+
+func parseOperation(raw map[string]any) (*operations.OpInstance, error) {
+	name, _ := raw["op"].(string)
+
+	op, ok := patchOperations[name]
+
+	if !ok {
+		return nil, errors.Errorf("unknown operation [%s]", name)
+	}
+
+	path, err := pointerField(raw, "path")
+
+	if err != nil {
+		return nil, err
+	}
+
+	args := []any{}
+
+	switch name {
+	case "add", "replace", "test":
+		value, ok := raw["value"]
+
+		if !ok {
+			return nil, errors.Errorf("%s operation requires a value", name)
+		}
+
+		args = append(args, value)
+	case "move", "copy":
+		from, err := pointerField(raw, "from")
+
+		if err != nil {
+			return nil, err
+		}
+
+		args = append(args, from)
+	}
+
+	return &operations.OpInstance{
+		Op:   op,
+		Name: name,
+		Path: path,
+		Args: args,
+	}, nil
+}
+
+func pointerField(raw map[string]any, field string) (types.PathElementSlice, error) {
+	s, ok := raw[field].(string)
+
+	if !ok {
+		return nil, errors.Errorf("%s should be a string", field)
+	}
+
+	return types.ParsePointer(s)
+}
+
+// Apply modifies the document only if all the operations succeeded,
+// otherwise it's restored to the original state
+func Apply(root types.Node, ops []*operations.OpInstance) error {
+	return operations.Atomically(root, func() error {
+		for idx, op := range ops {
+			if err := op.Apply(root); err != nil {
+				return errors.Wrapf(err, "operation %d (%s %s) failed", idx, op.Name, op.Path.Pointer())
+			}
+		}
+
+		return nil
+	})
+}
+
+// Add sets an object field or inserts an element into an array,
+// - as the last path element appends to the array
+func Add(root types.Node, path []types.PathElement, args ...any) error {
+	if len(path) == 0 {
+		return errors.Errorf("replacing the whole document is not supported")
+	}
+
+	parentPath, last := path[:len(path)-1], path[len(path)-1]
+
+	parent, err := operations.Traverse(root, parentPath)
+
+	if err != nil {
+		return err
+	}
+
+	if parent.NodeType() != types.NodeTypeArray {
+		return operations.Set(root, path, args[0])
+	}
+
+	idx := len(parent.Fields())
+
+	if last.ObjectField != "-" {
+		idx, err = types.ParseArrayIdx(last.ObjectField)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return parent.InsertField(types.PathElement{ArrayIdx: idx}, types.DeepCopy(args[0]))
+}
+
+// Remove deletes the value, it should exist
+func Remove(root types.Node, path []types.PathElement, args ...any) error {
+	if _, err := operations.Traverse(root, path); err != nil {
+		return err
+	}
+
+	return operations.Delete(root, path)
+}
+
+// Replace sets the value, it should exist
+func Replace(root types.Node, path []types.PathElement, args ...any) error {
+	if _, err := operations.Traverse(root, path); err != nil {
+		return err
+	}
+
+	return operations.Set(root, path, args[0])
+}
+
+// Move removes the value at the path from the first argument
+// and adds it to the path
+func Move(root types.Node, path []types.PathElement, args ...any) error {
+	from := args[0].(types.PathElementSlice)
+
+	if len(from) < len(path) && from.Pointer() == types.PathElementSlice(path[:len(from)]).Pointer() {
+		return errors.Errorf("value cannot be moved into one of its children")
+	}
+
+	value, err := operations.Traverse(root, from)
+
+	if err != nil {
+		return err
+	}
+
+	v := value.Value()
+
+	if err := Remove(root, from); err != nil {
+		return err
+	}
+
+	return Add(root, path, v)
+}
+
+// Copy adds the value at the path from the first argument to the path
+func Copy(root types.Node, path []types.PathElement, args ...any) error {
+	value, err := operations.Traverse(root, args[0].(types.PathElementSlice))
+
+	if err != nil {
+		return err
+	}
+
+	return Add(root, path, types.DeepCopy(value.Value()))
+}
+
+// Test checks that the value at the path is equal to the argument
+func Test(root types.Node, path []types.PathElement, args ...any) error {
+	value, err := operations.Traverse(root, path)
+
+	if err != nil {
+		return err
+	}
+
+	if !types.Equal(value.Value(), args[0]) {
+		return ErrTestFailed
+	}
+
+	return nil
+}
@@ -0,0 +1,258 @@
+package jsonpatch
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/can3p/sackmesser/pkg/traverse/simplejson"
+	"github.com/can3p/sackmesser/pkg/traverse/yamltree"
+)
+
+// most of the examples come from the appendix of RFC 6902
+func TestApply(t *testing.T) {
+	examples := []struct {
+		description string
+		initial     string
+		patch       string
+		expected    string
+		isErr       bool
+	}{
+		{
+			description: "add an object member",
+			initial:     `{ "foo": "bar" }`,
+			patch:       `[ { "op": "add", "path": "/baz", "value": "qux" } ]`,
+			expected:    `{ "baz": "qux", "foo": "bar" }`,
+		},
+		{
+			description: "add an array element",
+			initial:     `{ "foo": [ "bar", "baz" ] }`,
+			patch:       `[ { "op": "add", "path": "/foo/1", "value": "qux" } ]`,
+			expected:    `{ "foo": [ "bar", "qux", "baz" ] }`,
+		},
+		{
+			description: "add to the end of an array",
+			initial:     `{ "foo": [ "bar" ] }`,
+			patch:       `[ { "op": "add", "path": "/foo/-", "value": [ "abc", "def" ] } ]`,
+			expected:    `{ "foo": [ "bar", [ "abc", "def" ] ] }`,
+		},
+		{
+			description: "remove an object member",
+			initial:     `{ "baz": "qux", "foo": "bar" }`,
+			patch:       `[ { "op": "remove", "path": "/baz" } ]`,
+			expected:    `{ "foo": "bar" }`,
+		},
+		{
+			description: "remove an array element",
+			initial:     `{ "foo": [ "bar", "qux", "baz" ] }`,
+			patch:       `[ { "op": "remove", "path": "/foo/1" } ]`,
+			expected:    `{ "foo": [ "bar", "baz" ] }`,
+		},
+		{
+			description: "replace a value",
+			initial:     `{ "baz": "qux", "foo": "bar" }`,
+			patch:       `[ { "op": "replace", "path": "/baz", "value": "boo" } ]`,
+			expected:    `{ "baz": "boo", "foo": "bar" }`,
+		},
+		{
+			description: "move a value",
+			initial:     `{ "foo": { "bar": "baz", "waldo": "fred" }, "qux": { "corge": "grault" } }`,
+			patch:       `[ { "op": "move", "from": "/foo/waldo", "path": "/qux/thud" } ]`,
+			expected:    `{ "foo": { "bar": "baz" }, "qux": { "corge": "grault", "thud": "fred" } }`,
+		},
+		{
+			description: "move an array element",
+			initial:     `{ "foo": [ "all", "grass", "cows", "eat" ] }`,
+			patch:       `[ { "op": "move", "from": "/foo/1", "path": "/foo/3" } ]`,
+			expected:    `{ "foo": [ "all", "cows", "eat", "grass" ] }`,
+		},
+		{
+			description: "copy a value",
+			initial:     `{ "foo": { "bar": [ 1 ] } }`,
+			patch:       `[ { "op": "copy", "from": "/foo", "path": "/baz" }, { "op": "add", "path": "/baz/bar/-", "value": 2 } ]`,
+			expected:    `{ "foo": { "bar": [ 1 ] }, "baz": { "bar": [ 1, 2 ] } }`,
+		},
+		{
+			description: "test a value",
+			initial:     `{ "baz": "qux", "foo": [ "a", 2, "c" ] }`,
+			patch:       `[ { "op": "test", "path": "/baz", "value": "qux" }, { "op": "test", "path": "/foo/1", "value": 2.0 } ]`,
+			expected:    `{ "baz": "qux", "foo": [ "a", 2, "c" ] }`,
+		},
+		{
+			description: "escaped keys",
+			initial:     `{ "/": 9, "~1": 10 }`,
+			patch:       `[ { "op": "test", "path": "/~01", "value": 10 }, { "op": "remove", "path": "/~1" } ]`,
+			expected:    `{ "~1": 10 }`,
+		},
+		{
+			description: "empty keys",
+			initial:     `{ "": { "a": 1 } }`,
+			patch:       `[ { "op": "test", "path": "//a", "value": 1 }, { "op": "add", "path": "/", "value": 2 } ]`,
+			expected:    `{ "": 2 }`,
+		},
+		{
+			description: "negative index",
+			initial:     `{ "foo": [ 1, 2 ] }`,
+			patch:       `[ { "op": "remove", "path": "/foo/-1" } ]`,
+			isErr:       true,
+		},
+		{
+			description: "index with leading zeros",
+			initial:     `{ "foo": [ 1, 2 ] }`,
+			patch:       `[ { "op": "replace", "path": "/foo/01", "value": 3 } ]`,
+			isErr:       true,
+		},
+		{
+			description: "insert with leading zeros",
+			initial:     `{ "foo": [ 1, 2 ] }`,
+			patch:       `[ { "op": "add", "path": "/foo/00", "value": 3 } ]`,
+			isErr:       true,
+		},
+		{
+			description: "numeric keys of objects are fields",
+			initial:     `{ "foo": { "01": 1 } }`,
+			patch:       `[ { "op": "replace", "path": "/foo/01", "value": 3 } ]`,
+			expected:    `{ "foo": { "01": 3 } }`,
+		},
+		{
+			description: "add to the top level array",
+			initial:     `[ 1, 2 ]`,
+			patch:       `[ { "op": "add", "path": "/0", "value": 0 }, { "op": "add", "path": "/-", "value": 3 } ]`,
+			expected:    `[ 0, 1, 2, 3 ]`,
+		},
+		{
+			description: "move within the top level array",
+			initial:     `[ 1, 2, 3 ]`,
+			patch:       `[ { "op": "move", "from": "/0", "path": "/2" } ]`,
+			expected:    `[ 2, 3, 1 ]`,
+		},
+		{
+			description: "replace the whole document",
+			initial:     `{ "foo": "bar" }`,
+			patch:       `[ { "op": "replace", "path": "", "value": { "baz": "qux" } } ]`,
+			isErr:       true,
+		},
+		{
+			description: "failed test leaves the document untouched",
+			initial:     `{ "baz": "qux" }`,
+			patch:       `[ { "op": "remove", "path": "/baz" }, { "op": "test", "path": "/baz", "value": "bar" } ]`,
+			isErr:       true,
+		},
+		{
+			description: "remove a missing value",
+			initial:     `{ "foo": "bar" }`,
+			patch:       `[ { "op": "remove", "path": "/baz" } ]`,
+			isErr:       true,
+		},
+		{
+			description: "add to a missing parent",
+			initial:     `{ "foo": "bar" }`,
+			patch:       `[ { "op": "add", "path": "/baz/bat", "value": "qux" } ]`,
+			isErr:       true,
+		},
+		{
+			description: "add out of bounds",
+			initial:     `{ "foo": [ 1 ] }`,
+			patch:       `[ { "op": "add", "path": "/foo/2", "value": 2 } ]`,
+			isErr:       true,
+		},
+		{
+			description: "move into own child",
+			initial:     `{ "foo": { "bar": 1 } }`,
+			patch:       `[ { "op": "move", "from": "/foo", "path": "/foo/bar/baz" } ]`,
+			isErr:       true,
+		},
+	}
+
+	for idx, ex := range examples {
+		node := simplejson.MustParse([]byte(ex.initial))
+
+		ops, err := Parse([]byte(ex.patch))
+		assert.NoError(t, err, "[Ex %d - %s]", idx+1, ex.description)
+
+		err = Apply(node, ops)
+
+		if ex.isErr {
+			assert.Error(t, err, "[Ex %d - %s]", idx+1, ex.description)
+
+			expected := simplejson.MustParse([]byte(ex.initial))
+			assert.Equal(t, expected.Value(), node.Value(), "[Ex %d - %s]", idx+1, ex.description)
+			continue
+		} else {
+			assert.NoError(t, err, "[Ex %d - %s]", idx+1, ex.description)
+		}
+
+		expected := simplejson.MustParse([]byte(ex.expected))
+
+		assert.Equal(t, expected.Value(), node.Value(), "[Ex %d - %s]", idx+1, ex.description)
+	}
+}
+
+func TestApplyYaml(t *testing.T) {
+	examples := []struct {
+		description string
+		initial     string
+		patch       string
+		expected    string
+		isErr       bool
+	}{
+		{
+			description: "comments stay with the elements",
+			initial:     "l:\n  - a # first\n  - b # second\n",
+			patch:       `[ { "op": "add", "path": "/l/0", "value": "z" } ]`,
+			expected:    "l:\n  - z\n  - a # first\n  - b # second\n",
+		},
+		{
+			description: "non-string keys",
+			initial:     "r:\n  200: ok\n",
+			patch:       `[ { "op": "add", "path": "/r/x", "value": 1 } ]`,
+			expected:    "r:\n  200: ok\n  x: 1\n",
+		},
+		{
+			description: "merged keys cannot be modified",
+			initial:     "base: &base {arr: [1, 2]}\nb: {<<: *base}\n",
+			patch:       `[ { "op": "add", "path": "/c", "value": 1 }, { "op": "remove", "path": "/b/arr" } ]`,
+			isErr:       true,
+		},
+	}
+
+	for idx, ex := range examples {
+		node := yamltree.MustParse([]byte(ex.initial))
+
+		ops, err := Parse([]byte(ex.patch))
+		assert.NoError(t, err, "[Ex %d - %s]", idx+1, ex.description)
+
+		err = Apply(node, ops)
+
+		if ex.isErr {
+			assert.Error(t, err, "[Ex %d - %s]", idx+1, ex.description)
+
+			untouched, err := yamltree.MustParse([]byte(ex.initial)).Serialize()
+			assert.NoError(t, err, "[Ex %d - %s]", idx+1, ex.description)
+			ex.expected = string(untouched)
+		} else {
+			assert.NoError(t, err, "[Ex %d - %s]", idx+1, ex.description)
+		}
+
+		out, err := node.Serialize()
+		assert.NoError(t, err, "[Ex %d - %s]", idx+1, ex.description)
+		assert.Equal(t, ex.expected, string(out), "[Ex %d - %s]", idx+1, ex.description)
+	}
+}
+
+func TestParse(t *testing.T) {
+	examples := []struct {
+		description string
+		patch       string
+	}{
+		{description: "not an array", patch: `{ "op": "add" }`},
+		{description: "unknown operation", patch: `[ { "op": "inc", "path": "/a" } ]`},
+		{description: "missing value", patch: `[ { "op": "add", "path": "/a" } ]`},
+		{description: "missing from", patch: `[ { "op": "move", "path": "/a" } ]`},
+		{description: "path is not a pointer", patch: `[ { "op": "remove", "path": "a" } ]`},
+	}
+
+	for idx, ex := range examples {
+		_, err := Parse([]byte(ex.patch))
+		assert.Error(t, err, "[Ex %d - %s]", idx+1, ex.description)
+	}
+}
@@ -0,0 +1,69 @@
+package operations
+
+import (
+	"fmt"
+
+	"github.com/can3p/sackmesser/pkg/traverse/types"
+)
+
+// OpError is returned when an operation fails to be applied,
+// Idx is the index of the operation in the list
+type OpError struct {
+	Idx int
+	Op  *OpInstance
+	Err error
+}
+
+func (e *OpError) Error() string {
+	return fmt.Sprintf("failed to apply operation %d %s: %s", e.Idx+1, e.Op.Expression(), e.Err)
+}
+
+func (e *OpError) Unwrap() error {
+	return e.Err
+}
+
+// Atomically runs the modification of the node and brings
+// the node back to the original state if the modification failed
+func Atomically(root types.Node, modify func() error) error {
+	restore := root.Snapshot()
+
+	if err := modify(); err != nil {
+		restore()
+		return err
+	}
+
+	return nil
+}
+
+// ApplyAll applies the operations as a whole: if any of them fails, the document
+// is restored to the original state and *OpError of the failed operation is returned
+func ApplyAll(root types.Node, ops []*OpInstance) error {
+	return Atomically(root, func() error {
+		for idx, op := range ops {
+			if err := op.Apply(root); err != nil {
+				return &OpError{Idx: idx, Op: op, Err: err}
+			}
+		}
+
+		return nil
+	})
+}
+
+// ApplyEach applies every operation on its own. An operation that fails
+// leaves the document untouched and is skipped, errors of all the skipped
+// operations are returned in order
+func ApplyEach(root types.Node, ops []*OpInstance) []*OpError {
+	var failed []*OpError
+
+	for idx, op := range ops {
+		err := Atomically(root, func() error {
+			return op.Apply(root)
+		})
+
+		if err != nil {
+			failed = append(failed, &OpError{Idx: idx, Op: op, Err: err})
+		}
+	}
+
+	return failed
+}
@@ -0,0 +1,185 @@
+package operations
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/can3p/sackmesser/pkg/traverse/simplejson"
+	"github.com/can3p/sackmesser/pkg/traverse/types"
+	"github.com/can3p/sackmesser/pkg/traverse/yamltree"
+	"github.com/pkg/errors"
+)
+
+func TestApplyAll(t *testing.T) {
+	examples := []struct {
+		description string
+		initial     string
+		ops         string
+		expected    string
+		failedIdx   int
+		isErr       bool
+	}{
+		{
+			description: "all operations succeed",
+			initial:     `{ "a": 1, "b": [ 1 ] }`,
+			ops:         `set(a, 2); push(b, 2); del(c)`,
+			expected:    `{ "a": 2, "b": [ 1, 2 ] }`,
+		},
+		{
+			description: "failed operation leaves the document untouched",
+			initial:     `{ "a": 1, "b": [ 1 ] }`,
+			ops:         `set(a, 2); push(b, 2); pop(a)`,
+			failedIdx:   2,
+			isErr:       true,
+		},
+		{
+			description: "operations are applied in order",
+			initial:     `{ "a": 1 }`,
+			ops:         `del(a); push(a, 1)`,
+			failedIdx:   1,
+			isErr:       true,
+		},
+	}
+
+	for idx, ex := range examples {
+		root := simplejson.MustParse([]byte(ex.initial))
+
+		ops, err := NewParser().ParseProgram(ex.ops)
+		assert.NoError(t, err, "[Ex %d - %s]", idx+1, ex.description)
+
+		err = ApplyAll(root, ops)
+
+		if ex.isErr {
+			var opErr *OpError
+			assert.True(t, errors.As(err, &opErr), "[Ex %d - %s]", idx+1, ex.description)
+			assert.Equal(t, ex.failedIdx, opErr.Idx, "[Ex %d - %s]", idx+1, ex.description)
+
+			expected := simplejson.MustParse([]byte(ex.initial))
+			assert.Equal(t, expected.Value(), root.Value(), "[Ex %d - %s]", idx+1, ex.description)
+			continue
+		} else {
+			assert.NoError(t, err, "[Ex %d - %s]", idx+1, ex.description)
+		}
+
+		expected := simplejson.MustParse([]byte(ex.expected))
+		assert.Equal(t, expected.Value(), root.Value(), "[Ex %d - %s]", idx+1, ex.description)
+	}
+}
+
+func TestApplyEach(t *testing.T) {
+	examples := []struct {
+		description string
+		initial     string
+		ops         string
+		expected    string
+		failed      []int
+	}{
+		{
+			description: "nothing fails",
+			initial:     `{ "a": 1 }`,
+			ops:         `set(a, 2); set(b, 3)`,
+			expected:    `{ "a": 2, "b": 3 }`,
+		},
+		{
+			description: "failed operations are skipped",
+			initial:     `{ "a": 1, "b": [ 1 ] }`,
+			ops:         `pop(a); push(b, 2); set(a.c, 1); set(d, 4)`,
+			expected:    `{ "a": 1, "b": [ 1, 2 ], "d": 4 }`,
+			failed:      []int{0, 2},
+		},
+		{
+			description: "failed operation does not apply partially",
+			initial:     `{ "a": [ [], [ 1 ] ] }`,
+			ops:         `pop(a.*)`,
+			expected:    `{ "a": [ [], [ 1 ] ] }`,
+			failed:      []int{0},
+		},
+	}
+
+	for idx, ex := range examples {
+		root := simplejson.MustParse([]byte(ex.initial))
+
+		ops, err := NewParser().ParseProgram(ex.ops)
+		assert.NoError(t, err, "[Ex %d - %s]", idx+1, ex.description)
+
+		failed := []int{}
+
+		for _, opErr := range ApplyEach(root, ops) {
+			failed = append(failed, opErr.Idx)
+		}
+
+		if ex.failed == nil {
+			ex.failed = []int{}
+		}
+
+		assert.Equal(t, ex.failed, failed, "[Ex %d - %s]", idx+1, ex.description)
+
+		expected := simplejson.MustParse([]byte(ex.expected))
+		assert.Equal(t, expected.Value(), root.Value(), "[Ex %d - %s]", idx+1, ex.description)
+	}
+}
+
+func TestApplyAllYaml(t *testing.T) {
+	examples := []struct {
+		description string
+		initial     string
+		ops         string
+		expected    string
+		isErr       bool
+	}{
+		{
+			description: "non-string keys",
+			initial:     "responses:\n  200: ok\n",
+			ops:         `set(responses.x, 1)`,
+			expected:    "responses:\n  200: ok\n  x: 1\n",
+		},
+		{
+			description: "merged keys cannot be modified",
+			initial:     "base: &base {arr: [1, 2]}\nb: {<<: *base}\n",
+			ops:         `set(c, 1); pop(b.arr)`,
+			isErr:       true,
+		},
+	}
+
+	for idx, ex := range examples {
+		root := yamltree.MustParse([]byte(ex.initial))
+
+		ops, err := NewParser().ParseProgram(ex.ops)
+		assert.NoError(t, err, "[Ex %d - %s]", idx+1, ex.description)
+
+		err = ApplyAll(root, ops)
+
+		if ex.isErr {
+			assert.Error(t, err, "[Ex %d - %s]", idx+1, ex.description)
+
+			untouched, err := yamltree.MustParse([]byte(ex.initial)).Serialize()
+			assert.NoError(t, err, "[Ex %d - %s]", idx+1, ex.description)
+			ex.expected = string(untouched)
+		} else {
+			assert.NoError(t, err, "[Ex %d - %s]", idx+1, ex.description)
+		}
+
+		out, err := root.Serialize()
+		assert.NoError(t, err, "[Ex %d - %s]", idx+1, ex.description)
+		assert.Equal(t, ex.expected, string(out), "[Ex %d - %s]", idx+1, ex.description)
+	}
+}
+
+func TestApplyAllRunsOnce(t *testing.T) {
+	calls := 0
+
+	counter := func(root types.Node, path []types.PathElement, args ...any) error {
+		calls++
+		return Set(root, path, calls)
+	}
+
+	root := simplejson.MustParse([]byte(`{}`))
+	ops := []*OpInstance{{Op: counter, Name: "count", Path: testPath("a")}}
+
+	assert.NoError(t, ApplyAll(root, ops))
+	assert.Equal(t, 1, calls)
+	assert.Equal[any](t, map[string]any{"a": 1}, root.Value())
+
+	assert.Equal(t, 0, len(ApplyEach(root, ops)))
+	assert.Equal(t, 2, calls)
+}
@@ -5,13 +5,22 @@ import (
 )
 
 func Delete(root types.Node, path []types.PathElement, args ...any) error {
-	node, lastChunk, err := traverseButOne(root, path)
+	err := forEachPath(root, path, func(path []types.PathElement) error {
+		node, lastChunk, err := traverseButOne(root, path)
 
+		if err == types.ErrFieldMissing {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		return node.DeleteField(lastChunk)
+	})
+
+	// nothing to expand the wildcard against
 	if err == types.ErrFieldMissing {
 		return nil
-	} else if err != nil {
-		return err
 	}
 
-	return node.DeleteField(lastChunk)
+	return err
 }
@@ -37,6 +37,51 @@ func TestDeleteOperation(t *testing.T) {
 			path:        testPath("nonexistant"),
 			expected:    jstr,
 		},
+		{
+			description: "delete the last array item",
+			path:        testPath("abc", "def", -1),
+			expected:    `{ "abc": { "def": [ 1, 2 ] } }`,
+		},
+		{
+			description: "delete a slice",
+			path:        testPathSlice(t, "abc.def[0:2]"),
+			expected:    `{ "abc": { "def": [ 3 ] } }`,
+		},
+		{
+			description: "delete a slice with a step",
+			path:        testPathSlice(t, "abc.def[::-2]"),
+			expected:    `{ "abc": { "def": [ 2 ] } }`,
+		},
+		{
+			description: "delete every array item",
+			path:        testPath("abc", "def", "*"),
+			expected:    `{ "abc": { "def": [] } }`,
+		},
+		{
+			description: "delete every field",
+			path:        testPath("*"),
+			expected:    `{}`,
+		},
+		{
+			description: "delete array items matching the filter",
+			path:        append(testPath("abc", "def"), testFilter(t, `@ == 1 || @ == 3`)),
+			expected:    `{ "abc": { "def": [ 2 ] } }`,
+		},
+		{
+			description: "delete a field at every depth",
+			path:        testPath("..", "def"),
+			expected:    `{ "abc": {} }`,
+		},
+		{
+			description: "delete with recursive descent without matches is fine",
+			path:        testPath("..", "nonexistant"),
+			expected:    jstr,
+		},
+		{
+			description: "wildcard over a missing field is fine",
+			path:        testPath("nonexistant", "*"),
+			expected:    jstr,
+		},
 	}
 
 	for idx, ex := range examples {
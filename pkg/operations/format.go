@@ -0,0 +1,95 @@
+package operations
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/can3p/sackmesser/pkg/traverse/types"
+)
+
+var identRE = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Expression formats the operation the way Parser.Parse accepts it,
+// e.g. set(spec.replicas, 3)
+func (op *OpInstance) Expression() string {
+	var buf strings.Builder
+
+	buf.WriteString(op.Name)
+	buf.WriteRune('(')
+	buf.WriteString(FormatPath(op.Path))
+
+	for _, arg := range op.Args {
+		buf.WriteString(", ")
+		buf.WriteString(FormatValue(arg))
+	}
+
+	buf.WriteRune(')')
+
+	return buf.String()
+}
+
+// FormatPath formats a path the way the parser reads it,
+// fields that are not identifiers are quoted
+func FormatPath(path types.PathElementSlice) string {
+	var buf strings.Builder
+
+	for idx, p := range path {
+		switch {
+		case p.Wildcard:
+			buf.WriteString("[*]")
+		case p.Recursive:
+			buf.WriteString("..")
+		case p.Filter != nil:
+			buf.WriteString("[?(")
+			buf.WriteString(p.Filter.String())
+			buf.WriteString(")]")
+		case p.Slice != nil:
+			buf.WriteRune('[')
+			buf.WriteString(p.Slice.String())
+			buf.WriteRune(']')
+		case !p.IsField():
+			buf.WriteRune('[')
+			buf.WriteString(strconv.Itoa(p.ArrayIdx))
+			buf.WriteRune(']')
+		default:
+			if idx > 0 && !path[idx-1].Recursive {
+				buf.WriteRune('.')
+			}
+
+			if identRE.MatchString(p.ObjectField) && !isKeyword(p.ObjectField) {
+				buf.WriteString(p.ObjectField)
+			} else {
+				buf.WriteString(strconv.Quote(p.ObjectField))
+			}
+		}
+	}
+
+	return buf.String()
+}
+
+// FormatValue formats an argument, strings are always quoted
+// and everything else is written as JSON
+func FormatValue(v any) string {
+	if s, ok := v.(string); ok {
+		return strconv.Quote(s)
+	}
+
+	var buf bytes.Buffer
+
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+
+	if err := enc.Encode(v); err != nil {
+		return strconv.Quote(fmt.Sprint(v))
+	}
+
+	return strings.TrimSpace(buf.String())
+}
+
+func isKeyword(s string) bool {
+	return s == "true" || s == "false" || s == "null"
+}
@@ -0,0 +1,53 @@
+package operations
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/can3p/sackmesser/pkg/traverse/types"
+)
+
+func TestFormatPath(t *testing.T) {
+	examples := []struct {
+		input    string
+		expected string
+	}{
+		{input: `a.b[0]`, expected: `a.b[0]`},
+		{input: `a."b c".true`, expected: `a."b c"."true"`},
+		{input: `..password`, expected: `..password`},
+		{input: `a..b[-1]`, expected: `a..b[-1]`},
+		{input: `a.."b c"`, expected: `a.."b c"`},
+		{input: `a[*].b`, expected: `a[*].b`},
+		{input: `a.*`, expected: `a[*]`},
+		{input: `a[1:-1:2]`, expected: `a[1:-1:2]`},
+		{input: `a[?(@.port >= 8000 && !@.internal)].name`, expected: `a[?(@.port >= 8000 && !@.internal)].name`},
+		{input: `a.""`, expected: `a.""`},
+		{input: `a[?(@."b c" == 1)]`, expected: `a[?(@."b c" == 1)]`},
+	}
+
+	parser := NewParser()
+
+	for idx, ex := range examples {
+		path, err := parser.ParsePath(ex.input)
+		assert.NoError(t, err, "[%d - %s]", idx+1, ex.input)
+
+		formatted := FormatPath(path)
+		assert.Equal(t, ex.expected, formatted, "[%d - %s]", idx+1, ex.input)
+
+		reparsed, err := parser.ParsePath(formatted)
+		assert.NoError(t, err, "[%d - %s]", idx+1, ex.input)
+		assert.Equal(t, path.String(), reparsed.String(), "[%d - %s]", idx+1, ex.input)
+	}
+}
+
+func TestOpErrorMessage(t *testing.T) {
+	parser := NewParser()
+
+	for idx, ex := range []string{`push(..password, 1)`, `push(a[*], 1)`} {
+		op, err := parser.Parse(ex)
+		assert.NoError(t, err, "[%d - %s]", idx+1, ex)
+
+		opErr := &OpError{Op: op, Err: types.ErrWrongVisit}
+		assert.Contains(t, opErr.Error(), ex, "[%d - %s]", idx+1, ex)
+	}
+}
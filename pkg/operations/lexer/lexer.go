@@ -25,6 +25,8 @@ var (
 // It's custom because:
 // - string token can have different sets of quotes: single, double, backtick
 // - special token type - JSON
+// - special token type - Subscript, anything in square brackets that is not json, e.g. [*]
+// - special token type - Pointer, JSON Pointer, e.g. /spec/containers/0
 func NewCustomTextScannerLexer() lexer.Definition {
 	return &textScannerLexerDefinition{}
 }
@@ -38,12 +40,14 @@ func (d *textScannerLexerDefinition) Lex(filename string, r io.Reader) (lexer.Le
 
 func (d *textScannerLexerDefinition) Symbols() map[string]lexer.TokenType {
 	return map[string]lexer.TokenType{
-		"EOF":    lexer.EOF,
-		"Ident":  scanner.Ident,
-		"Int":    scanner.Int,
-		"Float":  scanner.Float,
-		"String": scanner.String,
-		"JSON":   scanner.JSON,
+		"EOF":       lexer.EOF,
+		"Ident":     scanner.Ident,
+		"Int":       scanner.Int,
+		"Float":     scanner.Float,
+		"String":    scanner.String,
+		"JSON":      scanner.JSON,
+		"Subscript": scanner.Subscript,
+		"Pointer":   scanner.Pointer,
 	}
 }
 
@@ -56,12 +56,15 @@ func (pos Position) String() string {
 // Use GoTokens to configure the Scanner such that it accepts all Go
 // literal tokens including Go identifiers. Comments will be skipped.
 const (
-	ScanIdents  = 1 << -Ident
-	ScanInts    = 1 << -Int
-	ScanFloats  = 1 << -Float // includes Ints and hexadecimal floats
-	ScanStrings = 1 << -String
-	ScanJSON    = 1 << -JSON
-	GoTokens    = ScanIdents | ScanFloats | ScanStrings | ScanJSON
+	ScanIdents     = 1 << -Ident
+	ScanInts       = 1 << -Int
+	ScanFloats     = 1 << -Float // includes Ints and hexadecimal floats
+	ScanStrings    = 1 << -String
+	ScanJSON       = 1 << -JSON
+	ScanSubscripts = 1 << -Subscript
+	ScanPointers   = 1 << -Pointer
+	SkipComments   = 1 << -Comment // # comments up to the end of the line are treated as white space
+	GoTokens       = ScanIdents | ScanFloats | ScanStrings | ScanJSON | ScanSubscripts | ScanPointers | SkipComments
 )
 
 // The result of Scan is one of these tokens or a Unicode character.
@@ -72,15 +75,21 @@ const (
 	Float
 	String
 	JSON
+	Subscript
+	Pointer
+	Comment
 )
 
 var tokenString = map[rune]string{
-	EOF:    "EOF",
-	Ident:  "Ident",
-	Int:    "Int",
-	Float:  "Float",
-	String: "String",
-	JSON:   "JSON",
+	EOF:       "EOF",
+	Ident:     "Ident",
+	Int:       "Int",
+	Float:     "Float",
+	String:    "String",
+	JSON:      "JSON",
+	Subscript: "Subscript",
+	Pointer:   "Pointer",
+	Comment:   "Comment",
 }
 
 // TokenString returns a printable string for a token or Unicode character.
@@ -602,6 +611,72 @@ func (s *Scanner) scanJSON() (ch rune) {
 	}
 }
 
+// scanPointer reads a JSON Pointer, e.g. /spec/containers/0. Pointer ends
+// with a white space, a comma or a closing parenthesis, pointers that contain
+// them should be quoted
+func (s *Scanner) scanPointer() rune {
+	ch := s.next()
+
+	for ch >= 0 && ch != ',' && ch != ')' && s.Whitespace&(1<<uint(ch)) == 0 {
+		ch = s.next()
+	}
+
+	return ch
+}
+
+// scanBrackets reads everything up to the matching closing bracket.
+// The result is a JSON token if it's a valid json array, e.g. [1, 2]
+// or [0], otherwise it's a Subscript, e.g. [*]
+func (s *Scanner) scanBrackets() rune {
+	depth := 1
+
+	for depth > 0 {
+		ch := s.next()
+
+		switch ch {
+		case '"', '\'', '`':
+			s.skipQuoted(ch)
+		case '[':
+			depth++
+		case ']':
+			depth--
+		}
+
+		if ch < 0 {
+			s.error("brackets not terminated")
+			break
+		}
+	}
+
+	var buf bytes.Buffer
+
+	buf.Write(s.tokBuf.Bytes())
+	if s.tokPos >= 0 {
+		buf.Write(s.srcBuf[s.tokPos:s.srcPos])
+	}
+
+	if s.Mode&ScanJSON != 0 && (s.Mode&ScanSubscripts == 0 || json.Valid(buf.Bytes())) {
+		return JSON
+	}
+
+	return Subscript
+}
+
+func (s *Scanner) skipQuoted(quote rune) {
+	for {
+		ch := s.next()
+
+		switch {
+		case ch < 0:
+			return
+		case ch == '\\':
+			s.next()
+		case ch == quote:
+			return
+		}
+	}
+}
+
 // Scan reads the next token or Unicode character from source and returns it.
 // It only recognizes tokens t for which the respective Mode bit (1<<-t) is set.
 // It returns EOF at the end of the source. It reports scanner errors (read and
@@ -614,9 +689,19 @@ func (s *Scanner) Scan() rune {
 	s.tokPos = -1
 	s.Line = 0
 
-	// skip white space
-	for s.Whitespace&(1<<uint(ch)) != 0 {
-		ch = s.next()
+	// skip white space and comments
+	for {
+		for s.Whitespace&(1<<uint(ch)) != 0 {
+			ch = s.next()
+		}
+
+		if ch != '#' || s.Mode&SkipComments == 0 {
+			break
+		}
+
+		for ch != '\n' && ch >= 0 {
+			ch = s.next()
+		}
 	}
 
 	// start collecting token text
@@ -673,8 +758,18 @@ func (s *Scanner) Scan() rune {
 			if isDecimal(ch) && s.Mode&ScanFloats != 0 {
 				tok, ch = s.scanNumber(ch, true)
 			}
+		case '/':
+			if s.Mode&ScanPointers != 0 {
+				ch = s.scanPointer()
+				tok = Pointer
+			} else {
+				ch = s.next()
+			}
 		case '[':
-			fallthrough
+			if s.Mode&(ScanJSON|ScanSubscripts) != 0 {
+				tok = s.scanBrackets()
+			}
+			ch = s.next()
 		case '{':
 			if s.Mode&ScanJSON != 0 {
 				s.scanJSON()
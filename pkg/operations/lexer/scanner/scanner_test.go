@@ -169,11 +169,15 @@ var tokenList = []token{
 	{JSON, `{ "abc": true }`},
 	{JSON, `[ 1, 2, 3]`},
 
+	{Subscript, `[*]`},
+	{Subscript, `[?(@.name == "]")]`},
+
 	// NUL character is not allowed
 	{'\x01', "\x01"},
 	{' ' - 1, string(' ' - 1)},
 	{'+', "+"},
-	{'/', "/"},
+	{Pointer, "/"},
+	{Pointer, "/a~1b/0/"},
 	{'.', "."},
 	{'~', "~"},
 	{'(', "("},
@@ -332,6 +336,21 @@ func TestScanSelectedMask(t *testing.T) {
 	// and ScanNumbers may return either Int or Float.
 	testScanSelectedMode(t, ScanStrings, String)
 	testScanSelectedMode(t, ScanJSON, JSON)
+	testScanSelectedMode(t, ScanSubscripts, Subscript)
+	testScanSelectedMode(t, ScanPointers, Pointer)
+}
+
+func TestScanComments(t *testing.T) {
+	const src = "# comment\na # another comment\n\"#\" { \"b\": \"#\" } #"
+	s := new(Scanner).Init(strings.NewReader(src))
+	checkTok(t, s, 2, s.Scan(), Ident, "a")
+	checkTok(t, s, 3, s.Scan(), String, `"#"`)
+	checkTok(t, s, 3, s.Scan(), JSON, `{ "b": "#" }`)
+	checkTok(t, s, 3, s.Scan(), EOF, "")
+
+	s = new(Scanner).Init(strings.NewReader(src))
+	s.Mode = GoTokens &^ SkipComments
+	checkTok(t, s, 1, s.Scan(), '#', "#")
 }
 
 func TestScanCustomIdent(t *testing.T) {
@@ -7,7 +7,7 @@ import (
 
 func Merge(root types.Node, path []types.PathElement, args ...any) error {
 	if len(args) != 1 {
-		return errors.Errorf("set operation expects one argument")
+		return errors.Errorf("merge operation expects one argument")
 	}
 
 	value, ok := args[0].(map[string]any)
@@ -15,24 +15,29 @@ func Merge(root types.Node, path []types.PathElement, args ...any) error {
 		return errors.Errorf("Merge expects a json as an argument")
 	}
 
-	node, fieldName, err := traverseButOne(root, path)
+	return forEachPath(root, path, func(path []types.PathElement) error {
+		node, fieldName, err := traverseButOne(root, path)
 
-	if err != nil {
-		return err
-	}
+		if err != nil {
+			return err
+		}
 
-	fieldVal, err := node.GetField(fieldName)
+		// every path gets its own copy, since merge reuses the value
+		value := types.DeepCopy(value).(map[string]any)
 
-	if err == types.ErrFieldMissing {
-		return node.SetField(fieldName, value)
-	}
+		fieldVal, err := node.GetField(fieldName)
 
-	if err != nil {
-		return err
-	}
+		if err == types.ErrFieldMissing {
+			return node.SetField(fieldName, value)
+		}
+
+		if err != nil {
+			return err
+		}
 
-	fieldVal = mergeObject(fieldVal, value)
-	return node.SetField(fieldName, fieldVal)
+		fieldVal = mergeObject(fieldVal, value)
+		return node.SetField(fieldName, fieldVal)
+	})
 }
 
 func mergeObject(existingValue any, value map[string]any) any {
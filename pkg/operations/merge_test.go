@@ -40,6 +40,13 @@ func TestMergeOperation(t *testing.T) {
 			initial:     jstr,
 			expected:    `{ "abc": { "def": { "cfa": [ 1, 2, 3 ] },  "new field": { "added": true } } }`,
 		},
+		{
+			description: "merge into the last array item",
+			path:        testPath("items", -1),
+			arg:         map[string]any{"added": true},
+			initial:     `{ "items": [ { "a": 1 }, { "b": 2 } ] }`,
+			expected:    `{ "items": [ { "a": 1 }, { "b": 2, "added": true } ] }`,
+		},
 		{
 			description: "non object target field means set",
 			path:        testPath("abc", "def"),
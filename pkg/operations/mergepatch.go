@@ -0,0 +1,110 @@
+package operations
+
+import (
+	"sort"
+
+	"github.com/can3p/sackmesser/pkg/traverse/types"
+	"github.com/pkg/errors"
+)
+
+// MergePatch applies RFC 7396 JSON Merge Patch to the value at the path:
+// objects are merged recursively, null deletes a key and any other
+// value replaces the existing one. Empty path means the whole document
+func MergePatch(root types.Node, path []types.PathElement, args ...any) error {
+	if len(args) != 1 {
+		return errors.Errorf("mergepatch operation expects one argument")
+	}
+
+	patch := args[0]
+
+	return forEachPath(root, path, func(path []types.PathElement) error {
+		if len(path) == 0 {
+			return mergePatchRoot(root, patch)
+		}
+
+		node, lastChunk, err := traverseButOne(root, path)
+
+		if err != nil {
+			return err
+		}
+
+		current, err := node.GetField(lastChunk)
+
+		if err == types.ErrFieldMissing {
+			current = nil
+		} else if err != nil {
+			return err
+		}
+
+		return node.SetField(lastChunk, mergePatch(current, patch))
+	})
+}
+
+// the root node cannot be replaced, hence the patch
+// is applied field by field
+func mergePatchRoot(root types.Node, patch any) error {
+	typed, ok := patch.(map[string]any)
+
+	if !ok || root.NodeType() != types.NodeTypeObject {
+		return errors.Errorf("only an object can be merged into the whole document")
+	}
+
+	keys := make([]string, 0, len(typed))
+
+	for key := range typed {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		field := types.Field(key)
+
+		if typed[key] == nil {
+			if err := root.DeleteField(field); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		current, err := root.GetField(field)
+
+		if err == types.ErrFieldMissing {
+			current = nil
+		} else if err != nil {
+			return err
+		}
+
+		if err := root.SetField(field, mergePatch(current, typed[key])); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func mergePatch(target any, patch any) any {
+	typed, ok := patch.(map[string]any)
+
+	if !ok {
+		return types.DeepCopy(patch)
+	}
+
+	out, ok := target.(map[string]any)
+
+	if !ok {
+		out = map[string]any{}
+	}
+
+	for key, value := range typed {
+		if value == nil {
+			delete(out, key)
+			continue
+		}
+
+		out[key] = mergePatch(out[key], value)
+	}
+
+	return out
+}
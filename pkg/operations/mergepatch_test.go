@@ -0,0 +1,133 @@
+package operations
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/can3p/sackmesser/pkg/traverse/simplejson"
+	"github.com/can3p/sackmesser/pkg/traverse/types"
+)
+
+// test vectors come from the appendix of RFC 7396, the document
+// is wrapped into an object to allow non object targets and patches
+func TestMergePatchVectors(t *testing.T) {
+	examples := []struct {
+		target   string
+		patch    string
+		expected string
+	}{
+		{target: `{"a":"b"}`, patch: `{"a":"c"}`, expected: `{"a":"c"}`},
+		{target: `{"a":"b"}`, patch: `{"b":"c"}`, expected: `{"a":"b","b":"c"}`},
+		{target: `{"a":"b"}`, patch: `{"a":null}`, expected: `{}`},
+		{target: `{"a":"b","b":"c"}`, patch: `{"a":null}`, expected: `{"b":"c"}`},
+		{target: `{"a":["b"]}`, patch: `{"a":"c"}`, expected: `{"a":"c"}`},
+		{target: `{"a":"c"}`, patch: `{"a":["b"]}`, expected: `{"a":["b"]}`},
+		{target: `{"a":{"b":"c"}}`, patch: `{"a":{"b":"d","c":null}}`, expected: `{"a":{"b":"d"}}`},
+		{target: `{"a":[{"b":"c"}]}`, patch: `{"a":[1]}`, expected: `{"a":[1]}`},
+		{target: `["a","b"]`, patch: `["c","d"]`, expected: `["c","d"]`},
+		{target: `{"a":"b"}`, patch: `["c"]`, expected: `["c"]`},
+		{target: `{"a":"foo"}`, patch: `null`, expected: `null`},
+		{target: `{"a":"foo"}`, patch: `"bar"`, expected: `"bar"`},
+		{target: `{"e":null}`, patch: `{"a":1}`, expected: `{"e":null,"a":1}`},
+		{target: `[1,2]`, patch: `{"a":"b","c":null}`, expected: `{"a":"b"}`},
+		{target: `{}`, patch: `{"a":{"bb":{"ccc":null}}}`, expected: `{"a":{"bb":{}}}`},
+	}
+
+	for idx, ex := range examples {
+		description := ex.target + " + " + ex.patch
+		node := simplejson.MustParse([]byte(`{ "doc": ` + ex.target + ` }`))
+
+		err := MergePatch(node, testPath("doc"), mustDecode(t, ex.patch))
+		assert.NoError(t, err, "[Ex %d - %s]", idx+1, description)
+
+		expected := simplejson.MustParse([]byte(`{ "doc": ` + ex.expected + ` }`))
+
+		assert.Equal(t, expected.Value(), node.Value(), "[Ex %d - %s]", idx+1, description)
+	}
+}
+
+func TestMergePatchOperation(t *testing.T) {
+	jstr := `{ "abc": { "def": { "cfa": [1, 2, 3] } }, "ghi": true }`
+
+	examples := []struct {
+		description string
+		path        []types.PathElement
+		arg         any
+		initial     string
+		expected    string
+		isErr       bool
+	}{
+		{
+			description: "patch the whole document",
+			path:        testPath(),
+			arg:         map[string]any{"ghi": nil, "abc": map[string]any{"def": map[string]any{"added": true}}},
+			initial:     jstr,
+			expected:    `{ "abc": { "def": { "cfa": [ 1, 2, 3 ], "added": true } } }`,
+		},
+		{
+			description: "deleting missing field from the whole document is fine",
+			path:        testPath(),
+			arg:         map[string]any{"missing": nil},
+			initial:     jstr,
+			expected:    jstr,
+		},
+		{
+			description: "whole document cannot be replaced",
+			path:        testPath(),
+			arg:         true,
+			initial:     jstr,
+			isErr:       true,
+		},
+		{
+			description: "only object document can be patched as a whole",
+			path:        testPath(),
+			arg:         map[string]any{"a": 1},
+			initial:     `[ 1, 2 ]`,
+			isErr:       true,
+		},
+		{
+			description: "missing field is created",
+			path:        testPath("abc", "new"),
+			arg:         map[string]any{"a": json.Number("1"), "b": nil},
+			initial:     jstr,
+			expected:    `{ "abc": { "def": { "cfa": [1, 2, 3] }, "new": { "a": 1 } }, "ghi": true }`,
+		},
+		{
+			description: "patch every matched field",
+			path:        testPath("items", "*"),
+			arg:         map[string]any{"a": nil, "c": json.Number("3")},
+			initial:     `{ "items": [ { "a": 1 }, { "a": 1, "b": 2 } ] }`,
+			expected:    `{ "items": [ { "c": 3 }, { "b": 2, "c": 3 } ] }`,
+		},
+	}
+
+	for idx, ex := range examples {
+		node := simplejson.MustParse([]byte(ex.initial))
+
+		err := MergePatch(node, ex.path, ex.arg)
+
+		if ex.isErr {
+			assert.Error(t, err, "[Ex %d - %s]", idx+1, ex.description)
+			continue
+		} else {
+			assert.NoError(t, err, "[Ex %d - %s]", idx+1, ex.description)
+		}
+
+		expected := simplejson.MustParse([]byte(ex.expected))
+
+		assert.Equal(t, expected.Value(), node.Value(), "[Ex %d - %s]", idx+1, ex.description)
+	}
+}
+
+func mustDecode(t *testing.T, s string) any {
+	var v any
+
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+
+	assert.NoError(t, dec.Decode(&v))
+
+	return v
+}
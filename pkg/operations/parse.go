@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/alecthomas/participle/v2"
+	participleLexer "github.com/alecthomas/participle/v2/lexer"
 	"github.com/can3p/sackmesser/pkg/operations/lexer"
 	"github.com/can3p/sackmesser/pkg/traverse/types"
 	"github.com/pkg/errors"
@@ -30,48 +31,106 @@ func (op *OpInstance) Apply(root types.Node) error {
 	return op.Op(root, op.Path, op.Args...)
 }
 
-var operations = map[string]Operation{
-	"set":   Set,
-	"del":   Delete,
-	"merge": Merge,
-	"pop":   Pop,
-	"push":  Push,
-}
-
 //nolint:govet
 type Call struct {
-	Name      string        `@Ident`
-	Path      []PathElement `"(" @@+`
-	Arguments []Argument    `( "," @@ )* ")"`
+	Pos       participleLexer.Position
+	Tokens    []participleLexer.Token
+	Name      string     `parser:"@Ident"`
+	Path      Path       `parser:"\"(\" @@"`
+	Arguments []Argument `parser:"( \",\" @@ )* \")\""`
+}
+
+// Script is a list of calls separated with ; or |, a new line
+// separates calls as well in files, see Parser.ParseScript
+type Script struct {
+	Calls []*ScriptCall `parser:"@@*"`
+}
+
+type ScriptCall struct {
+	Call      *Call `parser:"@@"`
+	Separated bool  `parser:"@( \";\" | \"|\" )?"`
 }
 
-//nolint:govet
 type Argument struct {
-	Float  *float64 `  @Float`
-	Int    *int     `| @Int`
-	Bool   *Boolean `| @("true" | "false")`
-	Null   bool     `| @"null"`
-	String *string  `| @String | @Ident`
-	JSON   *JSON    `| @JSON`
+	Pos    participleLexer.Position
+	Number *Number  `parser:"  @(\"-\"? (Float | Int))"`
+	Bool   *Boolean `parser:"| @(\"true\" | \"false\")"`
+	Null   bool     `parser:"| @\"null\""`
+	String *string  `parser:"| @String | @Ident"`
+	JSON   *JSON    `parser:"| @JSON"`
+}
+
+// Path is either a JSON Pointer or a list of path elements
+type Path struct {
+	Pointer  *Pointer      `parser:"  @Pointer"`
+	Elements []PathElement `parser:"| @@+"`
+}
+
+type Pointer struct {
+	Path types.PathElementSlice
+}
+
+func (b *Pointer) Capture(values []string) error {
+	path, err := types.ParsePointer(values[0])
+
+	if err != nil {
+		return err
+	}
+
+	b.Path = path
+	return nil
+}
+
+func (arg *Argument) Value() any {
+	switch {
+	case arg.Bool != nil:
+		return bool(*arg.Bool)
+	case arg.Number != nil:
+		return json.Number(*arg.Number)
+	case arg.String != nil:
+		return unquote(*arg.String)
+	case arg.JSON != nil:
+		return arg.JSON.Val
+	}
+
+	return nil
 }
 
-//nolint:govet
 type PathElement struct {
 	// potential foot gun there, I did not want to have a
 	// leading dot, but I could not write a grammar rule
 	// to exclude it from the first match only, hence
 	// I've made it optional
-	ObjectField StringPathElement   ` "."? (@String | @Ident)`
-	ArrayIdx    ArrIndexPathElement ` | @JSON`
+	ObjectField *StringPathElement  `parser:" \".\"? (@String | @Ident)"`
+	Wildcard    bool                `parser:" | \".\"? @\"*\""`
+	Recursive   bool                `parser:" | @( \".\" \".\" )"`
+	ArrayIdx    ArrIndexPathElement `parser:" | @JSON"`
+	Subscript   *Subscript          `parser:" | @Subscript"`
 }
 
 type StringPathElement string
 
 func (b *StringPathElement) Capture(values []string) error {
-	*b = StringPathElement(strings.Trim(values[0], "\"'`"))
+	*b = StringPathElement(unquote(values[0]))
 	return nil
 }
 
+// unquote strips the quotes, escape sequences are only
+// interpreted in double quoted strings, e.g. "a\"b"
+func unquote(s string) string {
+	if len(s) < 2 || s[0] != s[len(s)-1] || !strings.ContainsRune("\"'`", rune(s[0])) {
+		return s
+	}
+
+	if s[0] == '"' {
+		if unquoted, err := strconv.Unquote(s); err == nil {
+			return unquoted
+		}
+	}
+
+	return s[1 : len(s)-1]
+}
+
 type ArrIndexPathElement int
 
 var arrayAccessRE = regexp.MustCompile(`\[-?\d+\]`)
@@ -92,6 +151,89 @@ func (b *ArrIndexPathElement) Capture(values []string) error {
 	return nil
 }
 
+// Subscript is anything in square brackets that is not
+// an array index, e.g. [*], [1:-1] or [?(@.name == "app")]
+type Subscript struct {
+	Wildcard bool
+	Filter   types.Predicate
+	Slice    *types.Slice
+}
+
+func (b *Subscript) Capture(values []string) error {
+	inner := strings.TrimSpace(values[0][1 : len(values[0])-1])
+
+	if inner == "*" {
+		b.Wildcard = true
+		return nil
+	}
+
+	if strings.HasPrefix(inner, "?") {
+		filter, err := parsePredicate(inner[1:])
+
+		if err != nil {
+			return errors.Wrapf(err, "Invalid filter: %s", values[0])
+		}
+
+		// parentheses around the filter are a part of the syntax
+		if group, ok := filter.(*groupPredicate); ok {
+			filter = group.pred
+		}
+
+		b.Filter = filter
+		return nil
+	}
+
+	if strings.Contains(inner, ":") {
+		slice, err := parseSlice(inner)
+
+		if err != nil {
+			return errors.Wrapf(err, "Invalid slice: %s", values[0])
+		}
+
+		b.Slice = slice
+		return nil
+	}
+
+	return errors.Errorf("Unsupported subscript: %s", values[0])
+}
+
+// parseSlice parses start:end:step, every part is optional
+func parseSlice(s string) (*types.Slice, error) {
+	parts := strings.Split(s, ":")
+
+	if len(parts) > 3 {
+		return nil, errors.Errorf("slice can have at most three parts")
+	}
+
+	bounds := make([]*int, 3)
+
+	for idx, part := range parts {
+		part = strings.TrimSpace(part)
+
+		if part == "" {
+			continue
+		}
+
+		v, err := strconv.Atoi(part)
+
+		if err != nil {
+			return nil, err
+		}
+
+		bounds[idx] = &v
+	}
+
+	if bounds[2] != nil && *bounds[2] == 0 {
+		return nil, errors.Errorf("slice step cannot be zero")
+	}
+
+	return &types.Slice{
+		Start: bounds[0],
+		End:   bounds[1],
+		Step:  bounds[2],
+	}, nil
+}
+
 type JSON struct {
 	Val any
 }
@@ -101,7 +243,10 @@ type JSON struct {
 func (b *JSON) Capture(values []string) error {
 	var val any
 
-	if err := json.Unmarshal([]byte(values[0]), &val); err != nil {
+	dec := json.NewDecoder(strings.NewReader(values[0]))
+	dec.UseNumber()
+
+	if err := dec.Decode(&val); err != nil {
 		return err
 	}
 
@@ -109,6 +254,40 @@ func (b *JSON) Capture(values []string) error {
 	return nil
 }
 
+// Number keeps the literal the way it was written to avoid
+// any precision loss, e.g. 1.10 will stay 1.10. Literals that
+// are not valid json numbers, like 0x10 or 1_000, are normalized
+type Number json.Number
+
+func (b *Number) Capture(values []string) error {
+	literal := strings.Join(values, "")
+
+	if isJSONNumber(literal) {
+		*b = Number(literal)
+		return nil
+	}
+
+	if i, err := strconv.ParseInt(literal, 0, 64); err == nil {
+		*b = Number(strconv.FormatInt(i, 10))
+		return nil
+	}
+
+	f, err := strconv.ParseFloat(literal, 64)
+
+	if err != nil {
+		return err
+	}
+
+	*b = Number(strconv.FormatFloat(f, 'g', -1, 64))
+	return nil
+}
+
+func isJSONNumber(s string) bool {
+	var n json.Number
+
+	return json.Unmarshal([]byte(s), &n) == nil
+}
+
 type Boolean bool
 
 func (b *Boolean) Capture(values []string) error {
@@ -117,17 +296,57 @@ func (b *Boolean) Capture(values []string) error {
 }
 
 type Parser struct {
-	parser *participle.Parser[Call]
+	parser       *participle.Parser[Call]
+	scriptParser *participle.Parser[Script]
+	pathParser   *participle.Parser[Path]
+	pointerPaths bool
+	registry     *Registry
+}
+
+type ParserOption func(p *Parser)
+
+// WithPointerPaths makes the parser treat quoted paths that start with
+// a slash as JSON Pointers, e.g. "/a b/c,d". Unquoted pointers are
+// recognized regardless of the option
+func WithPointerPaths() ParserOption {
+	return func(p *Parser) {
+		p.pointerPaths = true
+	}
+}
+
+// WithRegistry makes the parser recognize operations from the
+// registry instead of the built-in ones, see DefaultRegistry
+func WithRegistry(r *Registry) ParserOption {
+	return func(p *Parser) {
+		p.registry = r
+	}
 }
 
-func NewParser() *Parser {
+func NewParser(opts ...ParserOption) *Parser {
 	parser := participle.MustBuild[Call](
 		participle.Lexer(lexer.NewCustomTextScannerLexer()),
 	)
 
-	return &Parser{
-		parser: parser,
+	scriptParser := participle.MustBuild[Script](
+		participle.Lexer(lexer.NewCustomTextScannerLexer()),
+	)
+
+	pathParser := participle.MustBuild[Path](
+		participle.Lexer(lexer.NewCustomTextScannerLexer()),
+	)
+
+	p := &Parser{
+		parser:       parser,
+		scriptParser: scriptParser,
+		pathParser:   pathParser,
+		registry:     DefaultRegistry(),
+	}
+
+	for _, opt := range opts {
+		opt(p)
 	}
+
+	return p
 }
 
 // We should be parsing things like
@@ -149,41 +368,99 @@ func (p *Parser) Parse(s string) (*OpInstance, error) {
 		return nil, err
 	}
 
+	return p.toOperation(parsed)
+}
+
+// ParseScript parses a list of operations, e.g. a file with one operation
+// per line. Operations are separated with ;, | or a new line, they can span
+// several lines and # starts a comment that lasts until the end of the line.
+// Errors point to the file, line and column
+func (p *Parser) ParseScript(filename string, s string) ([]*OpInstance, error) {
+	return p.parseCalls(filename, s, true)
+}
+
+// ParseProgram parses a sequence of operations separated
+// with ; or |, e.g. set(a, 1); del(b) | push(c, 2)
+func (p *Parser) ParseProgram(s string) ([]*OpInstance, error) {
+	ops, err := p.parseCalls("", s, false)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ops) == 0 {
+		return nil, errors.Errorf("at least one operation is expected")
+	}
+
+	return ops, nil
+}
+
+func (p *Parser) parseCalls(filename string, s string, newlineSeparates bool) ([]*OpInstance, error) {
+	parsed, err := p.scriptParser.ParseString(filename, s)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ops := make([]*OpInstance, 0, len(parsed.Calls))
+
+	for idx, item := range parsed.Calls {
+		if idx > 0 && !parsed.Calls[idx-1].Separated {
+			if !newlineSeparates {
+				return nil, errors.Errorf("%s: operations should be separated with ; or |", item.Call.Pos)
+			}
+
+			// the last token of a call is the closing parenthesis
+			prev := parsed.Calls[idx-1].Call
+
+			if prev.Tokens[len(prev.Tokens)-1].Pos.Line == item.Call.Pos.Line {
+				return nil, errors.Errorf("%s: operations should be separated with ;, | or a new line", item.Call.Pos)
+			}
+		}
+
+		op, err := p.toOperation(item.Call)
+
+		if err != nil {
+			return nil, err
+		}
+
+		ops = append(ops, op)
+	}
+
+	return ops, nil
+}
+
+// errors point to the position of the call or of the argument
+// that does not match the signature of the operation
+func (p *Parser) toOperation(parsed *Call) (*OpInstance, error) {
 	opName := strings.ToLower(parsed.Name)
 
-	op, opExists := operations[opName]
+	op, signature, opExists := p.registry.Lookup(opName)
 
 	if !opExists {
-		return nil, errors.Errorf("Operation [%s] is not supported", opName)
+		return nil, errors.Errorf("%s: Operation [%s] is not supported", parsed.Pos, opName)
+	}
+
+	path, err := p.toPath(parsed.Path)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s", parsed.Pos)
+	}
+
+	if len(parsed.Arguments) != len(signature.Args) {
+		return nil, errors.Errorf("%s: %s expects %d argument(s), got %d", parsed.Pos, signature.Usage(opName), len(signature.Args), len(parsed.Arguments))
 	}
 
 	args := []any{}
 
-	for _, arg := range parsed.Arguments {
-		switch {
-		case arg.Bool != nil:
-			args = append(args, bool(*arg.Bool))
-		case arg.Int != nil:
-			args = append(args, *arg.Int)
-		case arg.Float != nil:
-			args = append(args, *arg.Float)
-		case arg.String != nil:
-			args = append(args, strings.Trim(string(*arg.String), "\"'`"))
-		case arg.Null:
-			args = append(args, nil)
-		case arg.JSON != nil:
-			args = append(args, arg.JSON.Val)
+	for idx, arg := range parsed.Arguments {
+		value := arg.Value()
+
+		if err := signature.Args[idx].check(value); err != nil {
+			return nil, errors.Errorf("%s: argument %d (%s) of %s %s", arg.Pos, idx+1, signature.Args[idx].Name, opName, err.Error())
 		}
-	}
 
-	// I've duplicated types to keep parsing data structures
-	// and traversal api independent
-	path := make([]types.PathElement, 0, len(parsed.Path))
-	for _, p := range parsed.Path {
-		path = append(path, types.PathElement{
-			ObjectField: string(p.ObjectField),
-			ArrayIdx:    int(p.ArrayIdx),
-		})
+		args = append(args, value)
 	}
 
 	return &OpInstance{
@@ -193,3 +470,59 @@ func (p *Parser) Parse(s string) (*OpInstance, error) {
 		Args: args,
 	}, nil
 }
+
+// ParsePath parses a standalone path, e.g. field.another_field[0]
+// or /field/another_field/0
+func (p *Parser) ParsePath(s string) (types.PathElementSlice, error) {
+	parsed, err := p.pathParser.ParseString("", s)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return p.toPath(*parsed)
+}
+
+func (p *Parser) toPath(parsed Path) (types.PathElementSlice, error) {
+	if parsed.Pointer != nil {
+		return parsed.Pointer.Path, nil
+	}
+
+	if p.pointerPaths && len(parsed.Elements) == 1 && parsed.Elements[0].ObjectField != nil &&
+		strings.HasPrefix(string(*parsed.Elements[0].ObjectField), "/") {
+		return types.ParsePointer(string(*parsed.Elements[0].ObjectField))
+	}
+
+	return toTraversalPath(parsed.Elements)
+}
+
+// I've duplicated types to keep parsing data structures
+// and traversal api independent
+func toTraversalPath(parsed []PathElement) (types.PathElementSlice, error) {
+	path := make([]types.PathElement, 0, len(parsed))
+	for idx, p := range parsed {
+		if p.Recursive && (idx == len(parsed)-1 || parsed[idx+1].Recursive) {
+			return nil, errors.Errorf("recursive descent should be followed by a field, an index or a wildcard")
+		}
+
+		el := types.PathElement{
+			ArrayIdx:  int(p.ArrayIdx),
+			Wildcard:  p.Wildcard,
+			Recursive: p.Recursive,
+		}
+
+		if p.ObjectField != nil {
+			el = types.Field(string(*p.ObjectField))
+		}
+
+		if p.Subscript != nil {
+			el.Wildcard = p.Subscript.Wildcard
+			el.Filter = p.Subscript.Filter
+			el.Slice = p.Subscript.Slice
+		}
+
+		path = append(path, el)
+	}
+
+	return path, nil
+}
@@ -1,6 +1,7 @@
 package operations
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/alecthomas/assert/v2"
@@ -37,6 +38,20 @@ func TestParse(t *testing.T) {
 			ExpectedPath: testPath("field", "another field"),
 			ExpectedArgs: []any{true},
 		},
+		{
+			description:  "escape sequences are interpreted in double quoted strings",
+			input:        `set("a\"b", "x\ty")`,
+			ExpectedOp:   "set",
+			ExpectedPath: testPath(`a"b`),
+			ExpectedArgs: []any{"x\ty"},
+		},
+		{
+			description:  "escape sequences are kept in single quoted strings",
+			input:        `set(a, 'x\ty')`,
+			ExpectedOp:   "set",
+			ExpectedPath: testPath("a"),
+			ExpectedArgs: []any{`x\ty`},
+		},
 		{
 			description:  "test boolean",
 			input:        "set(field, true)",
@@ -49,7 +64,28 @@ func TestParse(t *testing.T) {
 			input:        "set(field, 12345)",
 			ExpectedOp:   "set",
 			ExpectedPath: testPath("field"),
-			ExpectedArgs: []any{12345},
+			ExpectedArgs: []any{json.Number("12345")},
+		},
+		{
+			description:  "test float keeps the literal",
+			input:        "set(field, 1.10)",
+			ExpectedOp:   "set",
+			ExpectedPath: testPath("field"),
+			ExpectedArgs: []any{json.Number("1.10")},
+		},
+		{
+			description:  "test negative number",
+			input:        "set(field, -9007199254740993)",
+			ExpectedOp:   "set",
+			ExpectedPath: testPath("field"),
+			ExpectedArgs: []any{json.Number("-9007199254740993")},
+		},
+		{
+			description:  "test number that is not a valid json is normalized",
+			input:        "set(field, 0x10)",
+			ExpectedOp:   "set",
+			ExpectedPath: testPath("field"),
+			ExpectedArgs: []any{json.Number("16")},
 		},
 		{
 			description:  "test string with single quotes",
@@ -105,7 +141,7 @@ func TestParse(t *testing.T) {
 			input:        `set(field, { "abc": [1,2, false] })`,
 			ExpectedOp:   "set",
 			ExpectedPath: testPath("field"),
-			ExpectedArgs: []any{map[string]any{"abc": []any{float64(1), float64(2), false}}},
+			ExpectedArgs: []any{map[string]any{"abc": []any{json.Number("1"), json.Number("2"), false}}},
 		},
 	}
 
@@ -136,3 +172,276 @@ func TestParse(t *testing.T) {
 		assert.Equal(t, ex.ExpectedArgs, parsed.Args, "[%d - %s] arguments mismatch", idx+1, ex.description)
 	}
 }
+
+func TestParsePath(t *testing.T) {
+	ex := []struct {
+		description  string
+		input        string
+		isError      bool
+		ExpectedPath []types.PathElement
+	}{
+		{
+			description:  "nested path",
+			input:        `field[0]."another field"`,
+			ExpectedPath: testPath("field", 0, "another field"),
+		},
+		{
+			description:  "wildcards",
+			input:        `spec.containers[*].*`,
+			ExpectedPath: testPath("spec", "containers", "*", "*"),
+		},
+		{
+			description:  "recursive descent",
+			input:        `spec..image`,
+			ExpectedPath: testPath("spec", "..", "image"),
+		},
+		{
+			description: "recursive descent at the end",
+			input:       `spec..`,
+			isError:     true,
+		},
+		{
+			description: "invalid filter",
+			input:       `field[?(@.name ==)]`,
+			isError:     true,
+		},
+		{
+			description:  "json pointer",
+			input:        `/spec/containers/0/a~1b~0c`,
+			ExpectedPath: testPath("spec", "containers", "0", "a/b~c"),
+		},
+		{
+			description:  "json pointer with empty keys",
+			input:        `/a//b/`,
+			ExpectedPath: testPath("a", "", "b", ""),
+		},
+		{
+			description:  "empty key",
+			input:        `a.""[0]`,
+			ExpectedPath: testPath("a", "", 0),
+		},
+		{
+			description:  "quoted json pointer is a field name by default",
+			input:        `"/a b"`,
+			ExpectedPath: testPath("/a b"),
+		},
+		{
+			description: "invalid json pointer escape",
+			input:       `/a~2`,
+			isError:     true,
+		},
+		{
+			description: "zero slice step",
+			input:       `field[::0]`,
+			isError:     true,
+		},
+		{
+			description: "too many slice parts",
+			input:       `field[1:2:3:4]`,
+			isError:     true,
+		},
+		{
+			description: "unsupported subscript",
+			input:       `field[abc]`,
+			isError:     true,
+		},
+		{
+			description: "empty path",
+			input:       "",
+			isError:     true,
+		},
+		{
+			description: "operation is not a path",
+			input:       "set(field, 1)",
+			isError:     true,
+		},
+	}
+
+	parser := NewParser()
+
+	for idx, ex := range ex {
+		parsed, err := parser.ParsePath(ex.input)
+
+		if ex.isError {
+			assert.Error(t, err, "[%d - %s]", idx+1, ex.description)
+			continue
+		}
+
+		assert.NoError(t, err, "[%d - %s]", idx+1, ex.description)
+		assert.Equal(t, types.PathElementSlice(ex.ExpectedPath), parsed, "[%d - %s]", idx+1, ex.description)
+	}
+}
+
+func TestParsePathString(t *testing.T) {
+	examples := []string{
+		`spec.containers[?(@.name == "app")].image`,
+		`spec.containers[?(@.port > 8000 && !(@.internal || @.name == "a]b"))]`,
+		`a..b[*][0]`,
+		`a[1:][:-1][::2][1:5:-1]`,
+	}
+
+	parser := NewParser()
+
+	for idx, ex := range examples {
+		parsed, err := parser.ParsePath(ex)
+		assert.NoError(t, err, "[%d - %s]", idx+1, ex)
+
+		assert.Equal(t, ex, parsed.String(), "[%d - %s]", idx+1, ex)
+	}
+}
+
+func TestParsePointer(t *testing.T) {
+	parser := NewParser(WithPointerPaths())
+
+	path, err := parser.ParsePath(`"/a b/c,d/~1"`)
+	assert.NoError(t, err)
+	assert.Equal(t, types.PathElementSlice(testPath("a b", "c,d", "/")), path)
+
+	op, err := parser.Parse(`set(/spec/ports/-1, 1)`)
+	assert.NoError(t, err)
+	assert.Equal(t, types.PathElementSlice(testPath("spec", "ports", "-1")), op.Path)
+
+	path, err = parser.ParsePath(`"field"`)
+	assert.NoError(t, err)
+	assert.Equal(t, types.PathElementSlice(testPath("field")), path)
+
+	path, err = parser.ParsePath(`"/"`)
+	assert.NoError(t, err)
+	assert.Equal(t, types.PathElementSlice(testPath("")), path)
+
+	assert.Equal(t, "/spec/a~1b~0c/0", types.PathElementSlice(testPath("spec", "a/b~c", 0)).Pointer())
+	assert.Equal(t, "/a//0", types.PathElementSlice(testPath("a", "", 0)).Pointer())
+}
+
+func TestParseScript(t *testing.T) {
+	script := `# bump the version
+set(version, "1.2.0") # inline comment
+
+merge(metadata, {
+  "labels": { "tier": "#1" }
+})
+del(legacy)
+`
+
+	ops, err := NewParser().ParseScript("ops.sack", script)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(ops))
+
+	assert.Equal(t, "set", ops[0].Name)
+	assert.Equal(t, []any{"1.2.0"}, ops[0].Args)
+	assert.Equal(t, "merge", ops[1].Name)
+	assert.Equal(t, []any{map[string]any{"labels": map[string]any{"tier": "#1"}}}, ops[1].Args)
+	assert.Equal(t, types.PathElementSlice(testPath("legacy")), ops[2].Path)
+
+	examples := []struct {
+		description string
+		script      string
+		position    string
+	}{
+		{
+			description: "syntax error",
+			script:      "set(a, 1)\n\nset(a 1)",
+			position:    "ops.sack:3:7",
+		},
+		{
+			description: "unknown operation",
+			script:      "# comment\n  inc(a)",
+			position:    "ops.sack:2:3",
+		},
+		{
+			description: "invalid path",
+			script:      "set(a, 1)\ndel(a..)",
+			position:    "ops.sack:2:1",
+		},
+		{
+			description: "operations on the same line",
+			script:      "set(a, 1); del(b)\nmerge(c,\n  {}) del(d)",
+			position:    "ops.sack:3:7",
+		},
+	}
+
+	for idx, ex := range examples {
+		_, err := NewParser().ParseScript("ops.sack", ex.script)
+		assert.Error(t, err, "[%d - %s]", idx+1, ex.description)
+		assert.Contains(t, err.Error(), ex.position, "[%d - %s]", idx+1, ex.description)
+	}
+}
+
+func TestParseProgram(t *testing.T) {
+	examples := []struct {
+		description string
+		input       string
+		expected    []string
+		isError     bool
+	}{
+		{
+			description: "single operation",
+			input:       `set(a, 1)`,
+			expected:    []string{"set"},
+		},
+		{
+			description: "semicolons",
+			input:       `set(a, 1); del(b);`,
+			expected:    []string{"set", "del"},
+		},
+		{
+			description: "pipes",
+			input:       `set(a, "x|y") | push(items[?(@.a || @.b)].list, 1)`,
+			expected:    []string{"set", "push"},
+		},
+		{
+			description: "empty program",
+			input:       ` ; `,
+			isError:     true,
+		},
+		{
+			description: "missing separator",
+			input:       `set(a, 1) set(b, 2)`,
+			isError:     true,
+		},
+		{
+			description: "new line is not a separator",
+			input:       "set(a, 1)\nset(b, 2)",
+			isError:     true,
+		},
+		{
+			description: "operations span several lines",
+			input:       "set(a,\n 1);\nset(b, 2)",
+			expected:    []string{"set", "set"},
+		},
+		{
+			description: "double separator",
+			input:       `set(a, 1);; del(b)`,
+			isError:     true,
+		},
+		{
+			description: "error points to the position",
+			input:       `set(a, 1); inc(b)`,
+			isError:     true,
+		},
+	}
+
+	parser := NewParser()
+
+	for idx, ex := range examples {
+		ops, err := parser.ParseProgram(ex.input)
+
+		if ex.isError {
+			assert.Error(t, err, "[%d - %s]", idx+1, ex.description)
+			continue
+		}
+
+		assert.NoError(t, err, "[%d - %s]", idx+1, ex.description)
+
+		names := []string{}
+
+		for _, op := range ops {
+			names = append(names, op.Name)
+		}
+
+		assert.Equal(t, ex.expected, names, "[%d - %s]", idx+1, ex.description)
+	}
+
+	_, err := parser.ParseProgram(`set(a, 1); inc(b)`)
+	assert.Contains(t, err.Error(), "1:12")
+}
@@ -5,22 +5,28 @@ import (
 )
 
 func Pop(root types.Node, path []types.PathElement, args ...any) error {
-	node, lastChunk, err := traverseButOne(root, path)
+	return forEachPath(root, path, func(path []types.PathElement) error {
+		node, lastChunk, err := traverseButOne(root, path)
 
-	if err != nil {
-		return err
-	}
+		if err != nil {
+			return err
+		}
 
-	val, err := node.GetField(lastChunk)
+		val, err := node.GetField(lastChunk)
 
-	if err != nil {
-		return err
-	}
+		if err != nil {
+			return err
+		}
 
-	typed, ok := val.([]any)
-	if !ok {
-		return types.ErrWrongVisit
-	}
+		typed, ok := val.([]any)
+		if !ok {
+			return types.ErrWrongVisit
+		}
 
-	return node.SetField(lastChunk, typed[:len(typed)-1])
+		if len(typed) == 0 {
+			return types.ErrIdxOutOfBounds
+		}
+
+		return node.SetField(lastChunk, typed[:len(typed)-1])
+	})
 }
@@ -14,6 +14,7 @@ func TestPopOperation(t *testing.T) {
 	examples := []struct {
 		description string
 		path        []types.PathElement
+		initial     string
 		expected    string
 		isErr       bool
 	}{
@@ -22,6 +23,18 @@ func TestPopOperation(t *testing.T) {
 			path:        testPath("abc"),
 			expected:    `{ "abc": [ 1, 2 ]}`,
 		},
+		{
+			description: "pop from the last nested array",
+			path:        testPath("nested", -1),
+			initial:     `{ "nested": [ [ 1 ], [ 2, 3 ] ] }`,
+			expected:    `{ "nested": [ [ 1 ], [ 2 ] ] }`,
+		},
+		{
+			description: "pop from empty array",
+			path:        testPath("nested", -1),
+			initial:     `{ "nested": [] }`,
+			isErr:       true,
+		},
 		{
 			description: "pop scalar",
 			path:        testPath("abc", 0),
@@ -30,7 +43,13 @@ func TestPopOperation(t *testing.T) {
 	}
 
 	for idx, ex := range examples {
-		node := simplejson.MustParse([]byte(jstr))
+		initial := jstr
+
+		if ex.initial != "" {
+			initial = ex.initial
+		}
+
+		node := simplejson.MustParse([]byte(initial))
 
 		err := Pop(node, ex.path)
 
@@ -0,0 +1,313 @@
+package operations
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/alecthomas/participle/v2"
+	"github.com/can3p/sackmesser/pkg/operations/lexer"
+	"github.com/can3p/sackmesser/pkg/traverse/types"
+)
+
+// Condition is a boolean expression made of comparisons, e.g.
+// kind == "Deployment" or @.port >= 8000 && !@.internal
+type Condition struct {
+	Or []*AndCondition `parser:"@@ ( \"|\" \"|\" @@ )*"`
+}
+
+type AndCondition struct {
+	And []*UnaryCondition `parser:"@@ ( \"&\" \"&\" @@ )*"`
+}
+
+type UnaryCondition struct {
+	Not        bool        `parser:"@\"!\"?"`
+	Group      *Condition  `parser:"( \"(\" @@ \")\""`
+	Comparison *Comparison `parser:"| @@ )"`
+}
+
+// Comparison compares the value at the path with a literal or checks
+// that the value exists if there is no operator. @ stands for the
+// current node and can be omitted if the path is not empty
+type Comparison struct {
+	Current bool          `parser:"( @\"@\""`
+	Path    []PathElement `parser:"  @@* | @@+ )"`
+	Op      string        `parser:"( @( \"=\" \"=\" | \"!\" \"=\" | \"<\" \"=\" | \">\" \"=\" | \"<\" | \">\" )"`
+	Value   *Argument     `parser:"  @@ )?"`
+}
+
+// filters are parsed while paths are captured, hence
+// the parser cannot be a part of the Parser struct
+var conditionParser = participle.MustBuild[Condition](
+	participle.Lexer(lexer.NewCustomTextScannerLexer()),
+)
+
+// ParsePredicate parses a condition, e.g. kind == "Deployment"
+func (p *Parser) ParsePredicate(s string) (types.Predicate, error) {
+	return parsePredicate(s)
+}
+
+func parsePredicate(s string) (types.Predicate, error) {
+	parsed, err := conditionParser.ParseString("", s)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return parsed.predicate()
+}
+
+func (c *Condition) predicate() (types.Predicate, error) {
+	out := orPredicate{}
+
+	for _, and := range c.Or {
+		pred, err := and.predicate()
+
+		if err != nil {
+			return nil, err
+		}
+
+		out = append(out, pred)
+	}
+
+	if len(out) == 1 {
+		return out[0], nil
+	}
+
+	return out, nil
+}
+
+func (c *AndCondition) predicate() (types.Predicate, error) {
+	out := andPredicate{}
+
+	for _, unary := range c.And {
+		pred, err := unary.predicate()
+
+		if err != nil {
+			return nil, err
+		}
+
+		out = append(out, pred)
+	}
+
+	if len(out) == 1 {
+		return out[0], nil
+	}
+
+	return out, nil
+}
+
+func (c *UnaryCondition) predicate() (types.Predicate, error) {
+	var pred types.Predicate
+	var err error
+
+	if c.Group != nil {
+		pred, err = c.Group.predicate()
+
+		if err != nil {
+			return nil, err
+		}
+
+		pred = &groupPredicate{pred: pred}
+	} else {
+		pred, err = c.Comparison.predicate()
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if c.Not {
+		return &notPredicate{pred: pred}, nil
+	}
+
+	return pred, nil
+}
+
+func (c *Comparison) predicate() (types.Predicate, error) {
+	path, err := toTraversalPath(c.Path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	out := &comparison{
+		current: c.Current,
+		path:    path,
+		op:      c.Op,
+	}
+
+	if c.Value != nil {
+		out.value = c.Value.Value()
+	}
+
+	return out, nil
+}
+
+type orPredicate []types.Predicate
+
+func (p orPredicate) Match(node types.Node) bool {
+	for _, pred := range p {
+		if pred.Match(node) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p orPredicate) String() string {
+	return joinPredicates(p, " || ")
+}
+
+type andPredicate []types.Predicate
+
+func (p andPredicate) Match(node types.Node) bool {
+	for _, pred := range p {
+		if !pred.Match(node) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (p andPredicate) String() string {
+	return joinPredicates(p, " && ")
+}
+
+func joinPredicates(preds []types.Predicate, sep string) string {
+	parts := make([]string, 0, len(preds))
+
+	for _, pred := range preds {
+		parts = append(parts, pred.String())
+	}
+
+	return strings.Join(parts, sep)
+}
+
+type notPredicate struct {
+	pred types.Predicate
+}
+
+func (p *notPredicate) Match(node types.Node) bool {
+	return !p.pred.Match(node)
+}
+
+func (p *notPredicate) String() string {
+	return "!" + p.pred.String()
+}
+
+// groupPredicate only exists to keep the parentheses in the string form
+type groupPredicate struct {
+	pred types.Predicate
+}
+
+func (p *groupPredicate) Match(node types.Node) bool {
+	return p.pred.Match(node)
+}
+
+func (p *groupPredicate) String() string {
+	return "(" + p.pred.String() + ")"
+}
+
+type comparison struct {
+	current bool
+	path    types.PathElementSlice
+	op      string
+	value   any
+}
+
+// missing value is not equal to anything and is not
+// less or greater than anything, the only exception is !=
+func (c *comparison) Match(node types.Node) bool {
+	target, err := Traverse(node, c.path)
+
+	if err != nil {
+		return c.op == "!="
+	}
+
+	switch c.op {
+	case "":
+		return true
+	case "==":
+		return types.Equal(target.Value(), c.value)
+	case "!=":
+		return !types.Equal(target.Value(), c.value)
+	}
+
+	cmp, ok := compare(target.Value(), c.value)
+
+	if !ok {
+		return false
+	}
+
+	switch c.op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+
+	return false
+}
+
+// compare orders numbers by value and strings lexicographically,
+// values of any other types or of different types cannot be compared
+func compare(a any, b any) (int, bool) {
+	if an, ok := types.ToNumber(a); ok {
+		bn, ok := types.ToNumber(b)
+
+		if !ok {
+			return 0, false
+		}
+
+		return an.Cmp(bn), true
+	}
+
+	as, ok := a.(string)
+
+	if !ok {
+		return 0, false
+	}
+
+	bs, ok := b.(string)
+
+	if !ok {
+		return 0, false
+	}
+
+	return strings.Compare(as, bs), true
+}
+
+// String quotes the fields the same way FormatPath does,
+// hence the result can be parsed back
+func (c *comparison) String() string {
+	path := FormatPath(c.path)
+
+	if c.current {
+		path = "@"
+
+		if len(c.path) > 0 && c.path[0].IsField() {
+			path += "."
+		}
+
+		path += FormatPath(c.path)
+	}
+
+	if c.op == "" {
+		return path
+	}
+
+	value, err := json.Marshal(c.value)
+
+	if err != nil {
+		return fmt.Sprintf("%s %s %v", path, c.op, c.value)
+	}
+
+	return fmt.Sprintf("%s %s %s", path, c.op, value)
+}
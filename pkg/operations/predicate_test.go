@@ -0,0 +1,146 @@
+package operations
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/can3p/sackmesser/pkg/traverse/simplejson"
+)
+
+func TestPredicate(t *testing.T) {
+	jstr := `{ "kind": "Deployment", "spec": { "replicas": 3, "labels": [ "a", "b" ] } }`
+
+	examples := []struct {
+		description string
+		input       string
+		expected    bool
+		isErr       bool
+	}{
+		{
+			description: "string equality",
+			input:       `kind == "Deployment"`,
+			expected:    true,
+		},
+		{
+			description: "bare word",
+			input:       `kind == Service`,
+			expected:    false,
+		},
+		{
+			description: "number is compared by value",
+			input:       `spec.replicas == 3.0`,
+			expected:    true,
+		},
+		{
+			description: "inequality",
+			input:       `spec.labels[1] != "b"`,
+			expected:    false,
+		},
+		{
+			description: "json value",
+			input:       `spec.labels == ["a", "b"]`,
+			expected:    true,
+		},
+		{
+			description: "missing field is not equal to anything",
+			input:       `metadata.name == null`,
+			expected:    false,
+		},
+		{
+			description: "missing field is not equal to anything, even null",
+			input:       `metadata.name != null`,
+			expected:    true,
+		},
+		{
+			description: "existence",
+			input:       `kind`,
+			expected:    true,
+		},
+		{
+			description: "negated existence",
+			input:       `!metadata`,
+			expected:    true,
+		},
+		{
+			description: "numeric comparison",
+			input:       `spec.replicas > 2 && spec.replicas <= 3`,
+			expected:    true,
+		},
+		{
+			description: "string comparison",
+			input:       `kind < "Service"`,
+			expected:    true,
+		},
+		{
+			description: "values of different types are not comparable",
+			input:       `kind > 2`,
+			expected:    false,
+		},
+		{
+			description: "or",
+			input:       `kind == Service || spec.replicas >= 3`,
+			expected:    true,
+		},
+		{
+			description: "groups",
+			input:       `!(kind == Service || metadata) && @.spec.labels[0] == a`,
+			expected:    true,
+		},
+		{
+			description: "current node",
+			input:       `@`,
+			expected:    true,
+		},
+		{
+			description: "incomplete expression",
+			input:       `kind ==`,
+			isErr:       true,
+		},
+		{
+			description: "empty path needs @",
+			input:       `== 1`,
+			isErr:       true,
+		},
+	}
+
+	parser := NewParser()
+
+	for idx, ex := range examples {
+		node := simplejson.MustParse([]byte(jstr))
+
+		pred, err := parser.ParsePredicate(ex.input)
+
+		if ex.isErr {
+			assert.Error(t, err, "[Ex %d - %s]", idx+1, ex.description)
+			continue
+		} else {
+			assert.NoError(t, err, "[Ex %d - %s]", idx+1, ex.description)
+		}
+
+		assert.Equal(t, ex.expected, pred.Match(node), "[Ex %d - %s]", idx+1, ex.description)
+	}
+}
+
+func TestPredicateString(t *testing.T) {
+	examples := []struct {
+		input    string
+		expected string
+	}{
+		{input: `kind=="Deployment"`, expected: `kind == "Deployment"`},
+		{input: `@.a.b>=1&&!(@[0]||c)`, expected: `@.a.b >= 1 && !(@[0] || c)`},
+		{input: `@."b c" == 1 && "d.e"`, expected: `@."b c" == 1 && "d.e"`},
+	}
+
+	parser := NewParser()
+
+	for idx, ex := range examples {
+		pred, err := parser.ParsePredicate(ex.input)
+		assert.NoError(t, err, "[Ex %d - %s]", idx+1, ex.input)
+
+		assert.Equal(t, ex.expected, pred.String(), "[Ex %d - %s]", idx+1, ex.input)
+
+		reparsed, err := parser.ParsePredicate(pred.String())
+		assert.NoError(t, err, "[Ex %d - %s]", idx+1, ex.input)
+		assert.Equal(t, ex.expected, reparsed.String(), "[Ex %d - %s]", idx+1, ex.input)
+	}
+}
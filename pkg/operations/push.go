@@ -7,28 +7,31 @@ import (
 
 func Push(root types.Node, path []types.PathElement, args ...any) error {
 	if len(args) != 1 {
-		return errors.Errorf("set operation expects one argument")
+		return errors.Errorf("push operation expects one argument")
 	}
 
 	value := args[0]
-	node, lastChunk, err := traverseButOne(root, path)
 
-	if err != nil {
-		return err
-	}
+	return forEachPath(root, path, func(path []types.PathElement) error {
+		node, lastChunk, err := traverseButOne(root, path)
 
-	val, err := node.GetField(lastChunk)
+		if err != nil {
+			return err
+		}
 
-	if err != nil {
-		return err
-	}
+		val, err := node.GetField(lastChunk)
 
-	typed, ok := val.([]any)
-	if !ok {
-		return types.ErrWrongVisit
-	}
+		if err != nil {
+			return err
+		}
+
+		typed, ok := val.([]any)
+		if !ok {
+			return types.ErrWrongVisit
+		}
 
-	typed = append(typed, value)
+		typed = append(typed, types.DeepCopy(value))
 
-	return node.SetField(lastChunk, typed)
+		return node.SetField(lastChunk, typed)
+	})
 }
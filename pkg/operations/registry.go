@@ -0,0 +1,227 @@
+package operations
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ArgKind is the kind of value an operation argument accepts
+type ArgKind string
+
+var (
+	ArgAny    ArgKind = "any"
+	ArgObject ArgKind = "object"
+	ArgString ArgKind = "string"
+	ArgInt    ArgKind = "integer"
+)
+
+// Arg describes an argument of an operation
+type Arg struct {
+	Name string
+	Kind ArgKind
+}
+
+// check returns an error if the value is not of the argument kind
+func (a Arg) check(value any) error {
+	ok := true
+
+	switch a.Kind {
+	case ArgObject:
+		_, ok = value.(map[string]any)
+	case ArgString:
+		_, ok = value.(string)
+	case ArgInt:
+		var n json.Number
+		n, ok = value.(json.Number)
+		ok = ok && isInt(n)
+	}
+
+	if !ok {
+		return errors.Errorf("should be %s, got %s", a.Kind.describe(), describeValue(value))
+	}
+
+	return nil
+}
+
+func isInt(n json.Number) bool {
+	_, err := strconv.Atoi(string(n))
+
+	return err == nil
+}
+
+func (k ArgKind) describe() string {
+	switch k {
+	case ArgObject:
+		return "an object"
+	case ArgString:
+		return "a string"
+	case ArgInt:
+		return "an integer"
+	}
+
+	return "any value"
+}
+
+func describeValue(value any) string {
+	switch value.(type) {
+	case map[string]any:
+		return "an object"
+	case []any:
+		return "an array"
+	case string:
+		return "a string"
+	case json.Number:
+		return "a number"
+	case bool:
+		return "a boolean"
+	case nil:
+		return "null"
+	}
+
+	return fmt.Sprintf("%T", value)
+}
+
+// Signature describes arguments an operation takes besides
+// the path and what the operation does. Parser checks the number
+// and kinds of arguments against the signature
+type Signature struct {
+	Args []Arg
+	Help string
+}
+
+// Usage formats the signature, e.g. set(path, value)
+func (s Signature) Usage(name string) string {
+	parts := []string{"path"}
+
+	for _, arg := range s.Args {
+		parts = append(parts, arg.Name)
+	}
+
+	return name + "(" + strings.Join(parts, ", ") + ")"
+}
+
+type registeredOp struct {
+	op        Operation
+	signature Signature
+}
+
+// Registry keeps operations the parser recognizes by name
+type Registry struct {
+	ops map[string]*registeredOp
+}
+
+// NewRegistry returns an empty registry, use DefaultRegistry
+// to get the one with the built-in operations
+func NewRegistry() *Registry {
+	return &Registry{
+		ops: map[string]*registeredOp{},
+	}
+}
+
+// DefaultRegistry returns a new registry with the built-in operations,
+// operations registered in it do not affect other registries
+func DefaultRegistry() *Registry {
+	r := NewRegistry()
+
+	r.MustRegister("set", Set, Signature{
+		Args: []Arg{{Name: "value", Kind: ArgAny}},
+		Help: "assign the value to the field",
+	})
+	r.MustRegister("del", Delete, Signature{
+		Help: "delete the field",
+	})
+	r.MustRegister("merge", Merge, Signature{
+		Args: []Arg{{Name: "value", Kind: ArgObject}},
+		Help: "merge the object into the field",
+	})
+	r.MustRegister("mergepatch", MergePatch, Signature{
+		Args: []Arg{{Name: "patch", Kind: ArgAny}},
+		Help: "apply JSON Merge Patch to the field, null deletes a key",
+	})
+	r.MustRegister("pop", Pop, Signature{
+		Help: "remove the last element of the array",
+	})
+	r.MustRegister("push", Push, Signature{
+		Args: []Arg{{Name: "value", Kind: ArgAny}},
+		Help: "append the value to the array",
+	})
+
+	return r
+}
+
+// Register adds an operation, names are case insensitive
+// and an operation cannot be registered twice
+func (r *Registry) Register(name string, op Operation, signature Signature) error {
+	name = strings.ToLower(name)
+
+	if !identRE.MatchString(name) {
+		return errors.Errorf("operation name should be an identifier: %s", name)
+	}
+
+	if _, exists := r.ops[name]; exists {
+		return errors.Errorf("operation [%s] is already registered", name)
+	}
+
+	r.ops[name] = &registeredOp{
+		op:        op,
+		signature: signature,
+	}
+
+	return nil
+}
+
+// MustRegister is the same as Register but panics on error
+func (r *Registry) MustRegister(name string, op Operation, signature Signature) {
+	if err := r.Register(name, op, signature); err != nil {
+		panic(err)
+	}
+}
+
+// Lookup returns the operation registered with the name
+func (r *Registry) Lookup(name string) (Operation, Signature, bool) {
+	registered, ok := r.ops[strings.ToLower(name)]
+
+	if !ok {
+		return nil, Signature{}, false
+	}
+
+	return registered.op, registered.signature, true
+}
+
+// Names returns names of all the registered operations in alphabetical order
+func (r *Registry) Names() []string {
+	out := make([]string, 0, len(r.ops))
+
+	for name := range r.ops {
+		out = append(out, name)
+	}
+
+	sort.Strings(out)
+
+	return out
+}
+
+// Help lists all the operations with their descriptions, one per line
+func (r *Registry) Help() string {
+	var buf strings.Builder
+
+	for _, name := range r.Names() {
+		sig := r.ops[name].signature
+
+		buf.WriteString(sig.Usage(name))
+
+		if sig.Help != "" {
+			buf.WriteString(" - ")
+			buf.WriteString(sig.Help)
+		}
+
+		buf.WriteRune('\n')
+	}
+
+	return buf.String()
+}
@@ -0,0 +1,89 @@
+package operations
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/can3p/sackmesser/pkg/traverse/simplejson"
+	"github.com/can3p/sackmesser/pkg/traverse/types"
+)
+
+func TestRegistry(t *testing.T) {
+	bump := func(root types.Node, path []types.PathElement, args ...any) error {
+		return Set(root, path, "2.0.0")
+	}
+
+	r := DefaultRegistry()
+
+	assert.NoError(t, r.Register("BumpVersion", bump, Signature{Help: "bump the major version"}))
+	assert.Error(t, r.Register("bumpversion", bump, Signature{}))
+	assert.Error(t, r.Register("set", bump, Signature{}))
+	assert.Error(t, r.Register("bump-version", bump, Signature{}))
+
+	_, _, ok := DefaultRegistry().Lookup("bumpversion")
+	assert.False(t, ok, "default registry should not be affected")
+
+	_, sig, ok := r.Lookup("BUMPVERSION")
+	assert.True(t, ok)
+	assert.Equal(t, "bump the major version", sig.Help)
+
+	op, err := NewParser(WithRegistry(r)).Parse(`bumpversion(version)`)
+	assert.NoError(t, err)
+
+	node := simplejson.MustParse([]byte(`{ "version": "1.0.0" }`))
+	assert.NoError(t, op.Apply(node))
+	assert.Equal[any](t, map[string]any{"version": "2.0.0"}, node.Value())
+
+	_, err = NewParser().Parse(`bumpversion(version)`)
+	assert.Error(t, err)
+
+	_, err = NewParser(WithRegistry(NewRegistry())).Parse(`set(version, 1)`)
+	assert.Error(t, err)
+}
+
+func TestRegistryHelp(t *testing.T) {
+	r := NewRegistry()
+
+	r.MustRegister("set", Set, Signature{Args: []Arg{{Name: "value", Kind: ArgAny}}, Help: "assign the value"})
+	r.MustRegister("del", Delete, Signature{})
+
+	assert.Equal(t, "del(path)\nset(path, value) - assign the value\n", r.Help())
+	assert.Equal(t, []string{"del", "set"}, r.Names())
+}
+
+func TestParseValidatesSignature(t *testing.T) {
+	r := DefaultRegistry()
+
+	r.MustRegister("nth", Set, Signature{Args: []Arg{{Name: "idx", Kind: ArgInt}, {Name: "label", Kind: ArgString}}})
+
+	examples := []struct {
+		input string
+		err   string
+	}{
+		{input: `set(a, 1)`},
+		{input: `merge(a, { "b": 1 })`},
+		{input: `nth(a, -2, "x")`},
+		{input: `set(a)`, err: "1:1: set(path, value) expects 1 argument(s), got 0"},
+		{input: `del(a, 1)`, err: "1:1: del(path) expects 0 argument(s), got 1"},
+		{input: `merge(a, "b")`, err: "1:10: argument 1 (value) of merge should be an object, got a string"},
+		{input: `merge(a, [ 1 ])`, err: "1:10: argument 1 (value) of merge should be an object, got an array"},
+		{input: `nth(a, 1.5, "x")`, err: "1:8: argument 1 (idx) of nth should be an integer, got a number"},
+		{input: `nth(a, 1, null)`, err: "1:11: argument 2 (label) of nth should be a string, got null"},
+	}
+
+	parser := NewParser(WithRegistry(r))
+
+	for idx, ex := range examples {
+		_, err := parser.Parse(ex.input)
+
+		if ex.err == "" {
+			assert.NoError(t, err, "[%d - %s]", idx+1, ex.input)
+			continue
+		}
+
+		assert.EqualError(t, err, ex.err, "[%d - %s]", idx+1, ex.input)
+	}
+
+	_, err := parser.ParseScript("ops.sack", "set(a, 1)\n  merge(a,\n    true)")
+	assert.EqualError(t, err, "ops.sack:3:5: argument 1 (value) of merge should be an object, got a boolean")
+}
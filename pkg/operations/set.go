@@ -12,11 +12,13 @@ func Set(root types.Node, path []types.PathElement, args ...any) error {
 
 	value := args[0]
 
-	node, lastChunk, err := traverseButOne(root, path)
+	return forEachPath(root, path, func(path []types.PathElement) error {
+		node, lastChunk, err := traverseButOne(root, path)
 
-	if err != nil {
-		return err
-	}
+		if err != nil {
+			return err
+		}
 
-	return node.SetField(lastChunk, value)
+		return node.SetField(lastChunk, types.DeepCopy(value))
+	})
 }
@@ -1,6 +1,7 @@
 package operations
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/alecthomas/assert/v2"
@@ -24,12 +25,10 @@ func TestSetOperation(t *testing.T) {
 			arg:         true,
 			expected:    `{ "abc": true }`,
 		},
-		// not testing integers since json parser parses everything into floats
-		// by default
 		{
 			description: "set existing field number",
 			path:        testPath("abc"),
-			arg:         1234.0,
+			arg:         json.Number("1234.0"),
 			expected:    `{ "abc": 1234.0 }`,
 		},
 		{
@@ -62,6 +61,78 @@ func TestSetOperation(t *testing.T) {
 			arg:         true,
 			expected:    `{ "abc": { "def": [ 1, 2, 3 ] }, "new field": true }`,
 		},
+		{
+			description: "negative index counts from the end",
+			path:        testPath("abc", "def", -1),
+			arg:         true,
+			expected:    `{ "abc": { "def": [ 1, 2, true ] } }`,
+		},
+		{
+			description: "negative index in object notation is not an array index",
+			path:        testPath("abc", "def", "-3"),
+			arg:         true,
+			isErr:       true,
+		},
+		{
+			description: "index with leading zeros in object notation",
+			path:        testPath("abc", "def", "01"),
+			arg:         true,
+			isErr:       true,
+		},
+		{
+			description: "index in object notation",
+			path:        testPath("abc", "def", "1"),
+			arg:         true,
+			expected:    `{ "abc": { "def": [ 1, true, 3 ] } }`,
+		},
+		{
+			description: "negative index out of bounds",
+			path:        testPath("abc", "def", -4),
+			arg:         true,
+			isErr:       true,
+		},
+		{
+			description: "set a slice",
+			path:        testPathSlice(t, "abc.def[1:]"),
+			arg:         true,
+			expected:    `{ "abc": { "def": [ 1, true, true ] } }`,
+		},
+		{
+			description: "slice of an object",
+			path:        testPathSlice(t, "abc[1:]"),
+			arg:         true,
+			isErr:       true,
+		},
+		{
+			description: "set every array item",
+			path:        testPath("abc", "def", "*"),
+			arg:         map[string]any{"one": "two"},
+			expected:    `{ "abc": { "def": [ { "one": "two" }, { "one": "two" }, { "one": "two" } ] } }`,
+		},
+		{
+			description: "set array items matching the filter",
+			path:        append(testPath("abc", "def"), testFilter(t, `@ >= 2`)),
+			arg:         json.Number("0"),
+			expected:    `{ "abc": { "def": [ 1, 0, 0 ] } }`,
+		},
+		{
+			description: "set a field at every depth",
+			path:        testPath("..", "def"),
+			arg:         false,
+			expected:    `{ "abc": { "def": false } }`,
+		},
+		{
+			description: "recursive descent does not add missing fields",
+			path:        testPath("..", "missing"),
+			arg:         false,
+			expected:    jstr,
+		},
+		{
+			description: "wildcard over a scalar",
+			path:        testPath("abc", "def", 0, "*"),
+			arg:         true,
+			isErr:       true,
+		},
 		{
 			description: "set array field",
 			path:        testPath("abc", "def", "0"),
@@ -14,6 +14,10 @@ func traverseButOne(root types.Node, path []types.PathElement) (types.Node, type
 		return root, path[0], nil
 	}
 
+	if hasWildcards(path) {
+		return nil, types.PathElement{}, errors.Errorf("path with wildcards should be expanded first")
+	}
+
 	var err error
 	// we do not want to traverse the last segment
 	// since all the operations usually work with the parent node
@@ -27,3 +31,201 @@ func traverseButOne(root types.Node, path []types.PathElement) (types.Node, type
 
 	return root, path[len(path)-1], nil
 }
+
+// Traverse visits every element of the path and returns the node it points to,
+// empty path returns the root itself
+func Traverse(root types.Node, path []types.PathElement) (types.Node, error) {
+	if hasWildcards(path) {
+		return nil, errors.Errorf("path with wildcards should be expanded first")
+	}
+
+	var err error
+
+	for _, p := range path {
+		root, err = root.Visit(p)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return root, nil
+}
+
+// ExpandPath replaces every wildcard in the path with the keys of an object
+// or indices of an array it points to, every slice with the indices it covers, every filter with the keys and indices
+// of the values the filter holds for, and every recursive descent with the paths
+// to the node itself and all of its descendants that have the next path element.
+// Paths are returned in the document order, parents go before their children,
+// a path without wildcards is returned as is
+func ExpandPath(root types.Node, path []types.PathElement) ([]types.PathElementSlice, error) {
+	for idx, p := range path {
+		if !p.Wildcard && !p.Recursive && p.Filter == nil && p.Slice == nil {
+			continue
+		}
+
+		node, err := Traverse(root, path[:idx])
+
+		if err != nil {
+			return nil, err
+		}
+
+		var prefixes []types.PathElementSlice
+
+		if p.Recursive {
+			// only existing matches are of interest, otherwise
+			// set(..debug, false) would add the field everywhere
+			prefixes, err = descendants(node, path[:idx], path[idx+1], nil)
+
+			if err != nil {
+				return nil, err
+			}
+		} else if p.Slice != nil {
+			prefixes, err = sliceItems(node, path[:idx], p.Slice)
+
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			prefixes, err = children(node, path[:idx], p.Filter)
+
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		out := []types.PathElementSlice{}
+
+		for _, prefix := range prefixes {
+			expanded, err := ExpandPath(root, appendPath(prefix, path[idx+1:]...))
+
+			if err != nil {
+				return nil, err
+			}
+
+			out = append(out, expanded...)
+		}
+
+		return out, nil
+	}
+
+	return []types.PathElementSlice{path}, nil
+}
+
+// children returns paths of all the children of the node
+// or only the ones the filter holds for
+func children(node types.Node, path types.PathElementSlice, filter types.Predicate) ([]types.PathElementSlice, error) {
+	if !isContainer(node) {
+		return nil, types.ErrWrongVisit
+	}
+
+	out := []types.PathElementSlice{}
+
+	for _, field := range node.Fields() {
+		if filter != nil {
+			child, err := node.Visit(field)
+
+			if err != nil {
+				return nil, err
+			}
+
+			if !filter.Match(child) {
+				continue
+			}
+		}
+
+		out = append(out, appendPath(path, field))
+	}
+
+	return out, nil
+}
+
+func sliceItems(node types.Node, path types.PathElementSlice, slice *types.Slice) ([]types.PathElementSlice, error) {
+	if node.NodeType() != types.NodeTypeArray {
+		return nil, types.ErrWrongVisit
+	}
+
+	out := []types.PathElementSlice{}
+
+	for _, idx := range slice.Indices(len(node.Fields())) {
+		out = append(out, appendPath(path, types.PathElement{ArrayIdx: idx}))
+	}
+
+	return out, nil
+}
+
+// descendants collects paths of the node and all of its descendants
+// that have the next path element, depth first
+func descendants(node types.Node, path types.PathElementSlice, next types.PathElement, out []types.PathElementSlice) ([]types.PathElementSlice, error) {
+	if next.Slice != nil {
+		if node.NodeType() == types.NodeTypeArray {
+			out = append(out, path)
+		}
+	} else if next.Wildcard || next.Filter != nil {
+		if isContainer(node) {
+			out = append(out, path)
+		}
+	} else if _, err := node.Visit(next); err == nil {
+		out = append(out, path)
+	}
+
+	for _, field := range node.Fields() {
+		child, err := node.Visit(field)
+
+		if err != nil {
+			return nil, err
+		}
+
+		out, err = descendants(child, appendPath(path, field), next, out)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return out, nil
+}
+
+func isContainer(node types.Node) bool {
+	t := node.NodeType()
+
+	return t == types.NodeTypeObject || t == types.NodeTypeArray
+}
+
+func appendPath(path []types.PathElement, elements ...types.PathElement) types.PathElementSlice {
+	out := make([]types.PathElement, 0, len(path)+len(elements))
+	out = append(out, path...)
+
+	return append(out, elements...)
+}
+
+// forEachPath calls fn for every path the wildcards expand to. Paths
+// are processed in the reverse order to keep indices of the remaining
+// array elements intact when elements are deleted. That also means
+// that nested matches are processed before the ones that contain them,
+// hence the paths of the outer matches are still valid when it's their turn
+func forEachPath(root types.Node, path []types.PathElement, fn func(path []types.PathElement) error) error {
+	paths, err := ExpandPath(root, path)
+
+	if err != nil {
+		return err
+	}
+
+	for idx := len(paths) - 1; idx >= 0; idx-- {
+		if err := fn(paths[idx]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func hasWildcards(path []types.PathElement) bool {
+	for _, p := range path {
+		if p.Wildcard || p.Recursive || p.Filter != nil || p.Slice != nil {
+			return true
+		}
+	}
+
+	return false
+}
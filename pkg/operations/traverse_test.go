@@ -14,8 +14,18 @@ func testPath(p ...any) []types.PathElement {
 	for _, p := range p {
 		str, ok := p.(string)
 
+		if ok && str == "*" {
+			out = append(out, types.PathElement{Wildcard: true})
+			continue
+		}
+
+		if ok && str == ".." {
+			out = append(out, types.PathElement{Recursive: true})
+			continue
+		}
+
 		if ok {
-			out = append(out, types.PathElement{ObjectField: str})
+			out = append(out, types.Field(str))
 			continue
 		}
 
@@ -26,6 +36,24 @@ func testPath(p ...any) []types.PathElement {
 	return out
 }
 
+func testFilter(t *testing.T, s string) types.PathElement {
+	t.Helper()
+
+	pred, err := parsePredicate(s)
+	assert.NoError(t, err)
+
+	return types.PathElement{Filter: pred}
+}
+
+func testPathSlice(t *testing.T, s string) []types.PathElement {
+	t.Helper()
+
+	path, err := NewParser().ParsePath(s)
+	assert.NoError(t, err)
+
+	return path
+}
+
 func TestTraverseButOne(t *testing.T) {
 	jstr := `{ "abc": { "def": { "cfa": "test" } } }`
 
@@ -85,3 +113,133 @@ func TestTraverseButOne(t *testing.T) {
 	}
 
 }
+
+func TestTraverse(t *testing.T) {
+	jstr := `{ "abc": [ true, { "def": { "cfa": "test" } } ] }`
+
+	examples := []struct {
+		description string
+		path        []types.PathElement
+		expected    string
+		isErr       bool
+	}{
+		{
+			description: "existing field",
+			path:        testPath("abc", 1, "def"),
+			expected:    `{ "cfa": "test" }`,
+		},
+		{
+			description: "empty path returns root",
+			path:        testPath(),
+			expected:    jstr,
+		},
+		{
+			description: "non existant path",
+			path:        testPath("abc", 2),
+			isErr:       true,
+		},
+	}
+
+	for idx, ex := range examples {
+		node := simplejson.MustParse([]byte(jstr))
+
+		node, err := Traverse(node, ex.path)
+
+		if ex.isErr {
+			assert.Error(t, err, "[Ex %d - %s]", idx+1, ex.description)
+			continue
+		} else {
+			assert.NoError(t, err, "[Ex %d - %s]", idx+1, ex.description)
+		}
+
+		expected := simplejson.MustParse([]byte(ex.expected))
+
+		assert.Equal(t, expected.Value(), node.Value(), "[Ex %d - %s]", idx+1, ex.description)
+	}
+}
+
+func TestExpandPath(t *testing.T) {
+	jstr := `{ "abc": [ { "def": { "b": 1, "a": 2 } }, { "def": { "c": 3 } } ], "str": "test" }`
+
+	examples := []struct {
+		description string
+		path        []types.PathElement
+		expected    []types.PathElementSlice
+		isErr       bool
+	}{
+		{
+			description: "path without wildcards is returned as is",
+			path:        testPath("abc", 1),
+			expected:    []types.PathElementSlice{testPath("abc", 1)},
+		},
+		{
+			description: "array and object wildcards",
+			path:        testPath("abc", "*", "def", "*"),
+			expected: []types.PathElementSlice{
+				testPath("abc", 0, "def", "a"),
+				testPath("abc", 0, "def", "b"),
+				testPath("abc", 1, "def", "c"),
+			},
+		},
+		{
+			description: "recursive descent keeps only existing matches, parents go first",
+			path:        testPath("..", "def"),
+			expected: []types.PathElementSlice{
+				testPath("abc", 0, "def"),
+				testPath("abc", 1, "def"),
+			},
+		},
+		{
+			description: "recursive descent followed by a wildcard",
+			path:        testPath("abc", 1, "..", "*"),
+			expected: []types.PathElementSlice{
+				testPath("abc", 1, "def"),
+				testPath("abc", 1, "def", "c"),
+			},
+		},
+		{
+			description: "filter over array items",
+			path:        append(testPath("abc"), testFilter(t, `def.c`)),
+			expected: []types.PathElementSlice{
+				testPath("abc", 1),
+			},
+		},
+		{
+			description: "filter over object values",
+			path:        append(testPath("abc", 0, "def"), testFilter(t, `@ > 1`)),
+			expected: []types.PathElementSlice{
+				testPath("abc", 0, "def", "a"),
+			},
+		},
+		{
+			description: "recursive descent without matches",
+			path:        testPath("..", "missing"),
+			expected:    []types.PathElementSlice{},
+		},
+		{
+			description: "wildcard over a scalar",
+			path:        testPath("str", "*"),
+			isErr:       true,
+		},
+		{
+			description: "wildcard over a missing field",
+			path:        testPath("missing", "*"),
+			isErr:       true,
+		},
+	}
+
+	for idx, ex := range examples {
+		node := simplejson.MustParse([]byte(jstr))
+
+		paths, err := ExpandPath(node, ex.path)
+
+		if ex.isErr {
+			assert.Error(t, err, "[Ex %d - %s]", idx+1, ex.description)
+			continue
+		} else {
+			assert.NoError(t, err, "[Ex %d - %s]", idx+1, ex.description)
+		}
+
+		assert.Equal(t, ex.expected, paths, "[Ex %d - %s]", idx+1, ex.description)
+	}
+}
@@ -0,0 +1,92 @@
+package sackmesser
+
+import (
+	"github.com/can3p/sackmesser/pkg/traverse/types"
+	"github.com/pkg/errors"
+)
+
+// SelectDocuments returns documents that match both the indices and the filter,
+// empty indices and nil filter match every document except for empty ones of
+// a multi-document stream. Indices that do not correspond to any document are an error
+func SelectDocuments(docs []types.Node, indices []int, filter types.Predicate) ([]types.Node, error) {
+	out := []types.Node{}
+
+	for _, idx := range indices {
+		if idx < 0 || idx >= len(docs) {
+			return nil, errors.Errorf("document index %d is out of range, there are %d documents", idx, len(docs))
+		}
+	}
+
+	for idx, doc := range docs {
+		if len(indices) > 0 && !containsInt(indices, idx) {
+			continue
+		}
+
+		if filter != nil && !filter.Match(doc) {
+			continue
+		}
+
+		// e.g. an empty document between two --- lines
+		if len(indices) == 0 && filter == nil && len(docs) > 1 && doc.NodeType() == types.NodeTypeNull {
+			continue
+		}
+
+		out = append(out, doc)
+	}
+
+	return out, nil
+}
+
+// Transform parses the input, calls modify for every document that matches
+// both the indices and the filter, see SelectDocuments, and serializes all
+// the documents in the original order into the output format. idx is the
+// index of the document in the input
+func Transform(input []byte, inputFormat string, outputFormat string, indices []int, filter types.Predicate, modify func(idx int, doc types.Node) error) ([]byte, error) {
+	docs, err := ParseDocuments(input, inputFormat)
+
+	if err != nil {
+		return nil, err
+	}
+
+	selected, err := SelectDocuments(docs, indices, filter)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for idx, doc := range docs {
+		if !isSelected(doc, selected) {
+			continue
+		}
+
+		if err := modify(idx, doc); err != nil {
+			if len(docs) > 1 {
+				return nil, errors.Wrapf(err, "document %d", idx)
+			}
+
+			return nil, err
+		}
+	}
+
+	return SerializeDocuments(docs, outputFormat)
+}
+
+func isSelected(doc types.Node, selected []types.Node) bool {
+	for _, s := range selected {
+		if s == doc {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsInt(l []int, v int) bool {
+	for _, item := range l {
+		if item == v {
+			return true
+		}
+	}
+
+	return false
+}
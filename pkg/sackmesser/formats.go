@@ -0,0 +1,124 @@
+package sackmesser
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/can3p/sackmesser/pkg/traverse/simplejson"
+	"github.com/can3p/sackmesser/pkg/traverse/simpletoml"
+	"github.com/can3p/sackmesser/pkg/traverse/simpleyaml"
+	"github.com/can3p/sackmesser/pkg/traverse/types"
+	"github.com/can3p/sackmesser/pkg/traverse/yamltree"
+)
+
+// Formats lists supported input and output formats
+var Formats = []string{"json", "yaml", "toml"}
+
+// FormatFromFilename guesses the format by the file
+// extension, json is used if the extension is unknown
+func FormatFromFilename(fname string) string {
+	switch strings.ToLower(filepath.Ext(fname)) {
+	case ".yaml", ".yml":
+		return "yaml"
+	case ".toml":
+		return "toml"
+	}
+
+	return "json"
+}
+
+// Parse parses a single document
+func Parse(input []byte, format string) (types.RootNode, error) {
+	switch format {
+	case "yaml":
+		return yamltree.Parse(input)
+	case "json":
+		return simplejson.ParseOrdered(input)
+	case "toml":
+		return simpletoml.Parse(input)
+	}
+
+	return nil, fmt.Errorf("Unkonwn input format: %s", format)
+}
+
+// Serialize keeps the formatting of the node if it's
+// serialized into the format it was parsed from
+func Serialize(node types.Node, format string) ([]byte, error) {
+	var outputRoot types.RootNode
+
+	switch format {
+	case "yaml":
+		outputRoot = simpleyaml.FromNode(node)
+	case "json":
+		outputRoot = simplejson.FromNodeOrdered(node)
+	case "toml":
+		outputRoot = simpletoml.FromNode(node)
+	default:
+		return nil, fmt.Errorf("Unkonwn ouput format: %s", format)
+	}
+
+	return outputRoot.Serialize()
+}
+
+// ParseDocuments returns every document of the input,
+// only yaml supports several documents in one input
+func ParseDocuments(input []byte, format string) ([]types.Node, error) {
+	if format != "yaml" {
+		root, err := Parse(input, format)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return []types.Node{root}, nil
+	}
+
+	roots, err := yamltree.ParseAll(input)
+
+	if err != nil {
+		return nil, err
+	}
+
+	docs := make([]types.Node, 0, len(roots))
+
+	for _, root := range roots {
+		docs = append(docs, root)
+	}
+
+	return docs, nil
+}
+
+// SerializeDocuments emits documents in the given order, yaml documents
+// are separated with ---, json ones are written one after another
+func SerializeDocuments(docs []types.Node, format string) ([]byte, error) {
+	if len(docs) == 1 {
+		return Serialize(docs[0], format)
+	}
+
+	switch format {
+	case "yaml":
+		return yamltree.SerializeAll(docs)
+	case "toml":
+		return nil, fmt.Errorf("toml does not support multiple documents, got %d", len(docs))
+	}
+
+	var buf bytes.Buffer
+
+	for _, doc := range docs {
+		out, err := Serialize(doc, format)
+
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(out)
+
+		if !bytes.HasSuffix(out, []byte("\n")) {
+			buf.WriteByte('\n')
+		}
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,318 @@
+// Package sackmesser applies sackmesser expressions, e.g. set(spec.replicas, 3),
+// to json, yaml and toml documents. It's the same machinery the command line
+// tool uses, hence the formatting of the documents is kept the same way:
+//
+//	out, err := sackmesser.Apply(input, "yaml", "yaml", `set(spec.replicas, 3); del(spec.paused)`)
+//
+// Programs can be compiled once and applied many times:
+//
+//	prog, err := sackmesser.Compile([]string{`set(spec.replicas, 3)`}, sackmesser.WithDocumentFilter(`kind == "Deployment"`))
+//	out, err := prog.Apply(input, "yaml", "yaml")
+//
+// Operations are applied to every document as a whole: if any of them fails,
+// the document is left untouched. WithContinueOnError skips failing operations instead.
+package sackmesser
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/can3p/sackmesser/pkg/operations"
+	"github.com/can3p/sackmesser/pkg/traverse/simpleobject"
+	"github.com/can3p/sackmesser/pkg/traverse/types"
+	"github.com/pkg/errors"
+)
+
+type script struct {
+	filename string
+	src      string
+}
+
+type config struct {
+	parserOpts []operations.ParserOption
+	scripts    []script
+	indices    []int
+	filter     string
+	lenient    bool
+}
+
+type Option func(c *config)
+
+// WithPointerPaths treats quoted paths that start
+// with a slash as JSON Pointers, see operations.WithPointerPaths
+func WithPointerPaths() Option {
+	return func(c *config) {
+		c.parserOpts = append(c.parserOpts, operations.WithPointerPaths())
+	}
+}
+
+// WithRegistry makes expressions use operations from the registry,
+// e.g. to add custom ones, see operations.DefaultRegistry
+func WithRegistry(r *operations.Registry) Option {
+	return func(c *config) {
+		c.parserOpts = append(c.parserOpts, operations.WithRegistry(r))
+	}
+}
+
+// WithScript adds operations from a script, e.g. a file with one operation
+// per line. Operations from scripts are applied before the expressions
+func WithScript(filename string, src string) Option {
+	return func(c *config) {
+		c.scripts = append(c.scripts, script{filename: filename, src: src})
+	}
+}
+
+// WithDocuments applies the operations only to the documents
+// with these indices of a multi-document yaml input
+func WithDocuments(indices ...int) Option {
+	return func(c *config) {
+		c.indices = append(c.indices, indices...)
+	}
+}
+
+// WithDocumentFilter applies the operations only to the documents
+// of a multi-document yaml input matching the condition, e.g. kind == "Deployment"
+func WithDocumentFilter(condition string) Option {
+	return func(c *config) {
+		c.filter = condition
+	}
+}
+
+// WithContinueOnError skips operations that fail instead of leaving the
+// document untouched. The result is returned together with *SkippedError
+// that lists the skipped operations
+func WithContinueOnError() Option {
+	return func(c *config) {
+		c.lenient = true
+	}
+}
+
+// SkippedError lists the operations skipped because of WithContinueOnError
+type SkippedError struct {
+	Failures []Failure
+}
+
+// Failure is a skipped operation, Doc is the index
+// of the document in a multi-document input
+type Failure struct {
+	Doc int
+	*operations.OpError
+}
+
+func (e *SkippedError) Error() string {
+	lines := make([]string, 0, len(e.Failures))
+
+	for _, f := range e.Failures {
+		lines = append(lines, fmt.Sprintf("document %d: %s", f.Doc, f.OpError))
+	}
+
+	return fmt.Sprintf("%d operation(s) skipped:\n%s", len(e.Failures), strings.Join(lines, "\n"))
+}
+
+// Program is a list of parsed operations ready to be applied
+type Program struct {
+	ops     []*operations.OpInstance
+	indices []int
+	filter  types.Predicate
+	lenient bool
+}
+
+// Compile parses the expressions, every expression can contain
+// several operations separated with ; or |
+func Compile(exprs []string, opts ...Option) (*Program, error) {
+	c := &config{}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	parser := operations.NewParser(c.parserOpts...)
+	prog := &Program{
+		ops:     []*operations.OpInstance{},
+		indices: c.indices,
+		lenient: c.lenient,
+	}
+
+	for _, s := range c.scripts {
+		ops, err := parser.ParseScript(s.filename, s.src)
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid operations file")
+		}
+
+		prog.ops = append(prog.ops, ops...)
+	}
+
+	for _, expr := range exprs {
+		ops, err := parser.ParseProgram(expr)
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid operation: [%s]", expr)
+		}
+
+		prog.ops = append(prog.ops, ops...)
+	}
+
+	if c.filter != "" {
+		filter, err := parser.ParsePredicate(c.filter)
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid document filter: [%s]", c.filter)
+		}
+
+		prog.filter = filter
+	}
+
+	return prog, nil
+}
+
+// Operations returns parsed operations in the order they are applied
+func (p *Program) Operations() []*operations.OpInstance {
+	return p.ops
+}
+
+// Apply parses the input, applies the operations to the selected documents
+// and serializes all the documents into the output format. With WithContinueOnError
+// the output is returned along with *SkippedError if some operations were skipped
+func (p *Program) Apply(input []byte, inputFormat string, outputFormat string) ([]byte, error) {
+	skipped := &SkippedError{}
+
+	out, err := Transform(input, inputFormat, outputFormat, p.indices, p.filter, func(idx int, doc types.Node) error {
+		err := p.ApplyToNode(doc)
+
+		var docSkipped *SkippedError
+
+		if !errors.As(err, &docSkipped) {
+			return err
+		}
+
+		for _, f := range docSkipped.Failures {
+			skipped.Failures = append(skipped.Failures, Failure{Doc: idx, OpError: f.OpError})
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(skipped.Failures) > 0 {
+		return out, skipped
+	}
+
+	return out, nil
+}
+
+// ApplyToNode applies the operations to the node in place. If any of them
+// fails, the node is left untouched, unless WithContinueOnError is used, in which
+// case only the failing operations are skipped and *SkippedError is returned
+func (p *Program) ApplyToNode(node types.Node) error {
+	if !p.lenient {
+		return operations.ApplyAll(node, p.ops)
+	}
+
+	failed := operations.ApplyEach(node, p.ops)
+
+	if len(failed) == 0 {
+		return nil
+	}
+
+	skipped := &SkippedError{}
+
+	for _, f := range failed {
+		skipped.Failures = append(skipped.Failures, Failure{OpError: f})
+	}
+
+	return skipped
+}
+
+// ApplyToValue applies the operations to a copy of the value, the value
+// should be made of map[string]any, []any and scalars, e.g. the result
+// of json.Unmarshal into any. Document selection does not apply to values.
+// Numbers set by the operations are float64, the same as json.Unmarshal
+// produces, unless the value holds json.Number already, i.e. it has been
+// decoded with UseNumber. With WithContinueOnError the result is returned
+// along with *SkippedError
+func (p *Program) ApplyToValue(v any) (any, error) {
+	root := simpleobject.FromValue(types.DeepCopy(v))
+
+	err := p.ApplyToNode(root)
+
+	var skipped *SkippedError
+
+	if err != nil && !errors.As(err, &skipped) {
+		return nil, err
+	}
+
+	if hasNumbers(v) {
+		return root.Value(), err
+	}
+
+	return toFloats(root.Value()), err
+}
+
+// hasNumbers reports whether there is a json.Number anywhere in the value
+func hasNumbers(v any) bool {
+	switch typed := v.(type) {
+	case json.Number:
+		return true
+	case map[string]any:
+		for _, value := range typed {
+			if hasNumbers(value) {
+				return true
+			}
+		}
+	case []any:
+		for _, value := range typed {
+			if hasNumbers(value) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// toFloats replaces json.Number values in place
+func toFloats(v any) any {
+	switch typed := v.(type) {
+	case json.Number:
+		if f, err := typed.Float64(); err == nil {
+			return f
+		}
+	case map[string]any:
+		for key, value := range typed {
+			typed[key] = toFloats(value)
+		}
+	case []any:
+		for idx, value := range typed {
+			typed[idx] = toFloats(value)
+		}
+	}
+
+	return v
+}
+
+// Apply applies the expressions to the input, see Program.Apply
+func Apply(input []byte, inputFormat string, outputFormat string, exprs ...string) ([]byte, error) {
+	prog, err := Compile(exprs)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return prog.Apply(input, inputFormat, outputFormat)
+}
+
+// ApplyToValue applies the expressions to a copy of the value, see Program.ApplyToValue
+func ApplyToValue(v any, exprs ...string) (any, error) {
+	prog, err := Compile(exprs)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return prog.ApplyToValue(v)
+}
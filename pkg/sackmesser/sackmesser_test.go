@@ -0,0 +1,234 @@
+package sackmesser
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/can3p/sackmesser/pkg/jsonpatch"
+	"github.com/can3p/sackmesser/pkg/operations"
+	"github.com/can3p/sackmesser/pkg/traverse/types"
+	"github.com/pkg/errors"
+)
+
+func TestApply(t *testing.T) {
+	examples := []struct {
+		description  string
+		input        string
+		inputFormat  string
+		outputFormat string
+		exprs        []string
+		expected     string
+		isErr        bool
+	}{
+		{
+			description:  "yaml formatting is kept",
+			input:        "# comment\na: 1 # one\nb: [1, 2]\n",
+			inputFormat:  "yaml",
+			outputFormat: "yaml",
+			exprs:        []string{`set(a, 2); push(b, 3)`},
+			expected:     "# comment\na: 2 # one\nb: [1, 2, 3]\n",
+		},
+		{
+			description:  "anchored node is not changed through an alias",
+			input:        "base: &base\n  x: 1\nuse: *base\n",
+			inputFormat:  "yaml",
+			outputFormat: "yaml",
+			exprs:        []string{`set(use.x, 7)`},
+			expected:     "base: &base\n  x: 1\nuse:\n  x: 7\n",
+		},
+		{
+			description:  "empty documents are skipped",
+			input:        "a: 1\n---\n---\nb: 2\n---\n",
+			inputFormat:  "yaml",
+			outputFormat: "yaml",
+			exprs:        []string{`set(x, 1)`},
+			expected:     "a: 1\nx: 1\n---\n---\nb: 2\nx: 1\n---\n",
+		},
+		{
+			description:  "format conversion",
+			input:        `{ "a": { "b": 1 } }`,
+			inputFormat:  "json",
+			outputFormat: "yaml",
+			exprs:        []string{`del(a.b)`, `set(c, "x")`},
+			expected:     "a: {}\nc: x\n",
+		},
+		{
+			description:  "big integers are plain yaml scalars",
+			input:        `{ "a": 123456789012345678901234567890, "b": 1.10 }`,
+			inputFormat:  "json",
+			outputFormat: "yaml",
+			exprs:        []string{`set(c, 98765432109876543210)`},
+			expected:     "a: 123456789012345678901234567890\nb: 1.10\nc: 98765432109876543210\n",
+		},
+		{
+			description:  "invalid expression",
+			input:        `{}`,
+			inputFormat:  "json",
+			outputFormat: "json",
+			exprs:        []string{`set(a`},
+			isErr:        true,
+		},
+		{
+			description:  "failed operation",
+			input:        `{ "a": 1 }`,
+			inputFormat:  "json",
+			outputFormat: "json",
+			exprs:        []string{`push(a, 1)`},
+			isErr:        true,
+		},
+	}
+
+	for idx, ex := range examples {
+		out, err := Apply([]byte(ex.input), ex.inputFormat, ex.outputFormat, ex.exprs...)
+
+		if ex.isErr {
+			assert.Error(t, err, "[Ex %d - %s]", idx+1, ex.description)
+			continue
+		} else {
+			assert.NoError(t, err, "[Ex %d - %s]", idx+1, ex.description)
+		}
+
+		assert.Equal(t, ex.expected, string(out), "[Ex %d - %s]", idx+1, ex.description)
+	}
+}
+
+func TestCompile(t *testing.T) {
+	input := "kind: Deployment\nreplicas: 1\n---\nkind: Service\n---\nkind: Deployment\nreplicas: 2\n"
+
+	prog, err := Compile(
+		[]string{`set(replicas, 3)`},
+		WithScript("ops.sack", "# comment\nset(managed, true)\n"),
+		WithDocumentFilter(`kind == "Deployment"`),
+		WithDocuments(2),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(prog.Operations()))
+
+	out, err := prog.Apply([]byte(input), "yaml", "yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, "kind: Deployment\nreplicas: 1\n---\nkind: Service\n---\nkind: Deployment\nreplicas: 3\nmanaged: true\n", string(out))
+
+	prog, err = Compile([]string{`set(x, 1)`}, WithDocuments(1))
+	assert.NoError(t, err)
+
+	_, err = prog.Apply([]byte("a: 1\n---\n---\nb: 2\n"), "yaml", "yaml")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "document 1:")
+
+	_, err = Compile(nil, WithDocumentFilter(`kind ==`))
+	assert.Error(t, err)
+
+	_, err = Compile(nil, WithScript("ops.sack", "set(a, 1)\nset(b"))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "ops.sack:2:")
+}
+
+func TestTransform(t *testing.T) {
+	input := "# first\nkind: Deployment\nreplicas: 1\n---\nkind: Service\n---\nkind: Deployment\n"
+
+	ops, err := jsonpatch.Parse([]byte(`[{ "op": "add", "path": "/replicas", "value": 3 }]`))
+	assert.NoError(t, err)
+
+	patch := func(_ int, doc types.Node) error {
+		return jsonpatch.Apply(doc, ops)
+	}
+
+	out, err := Transform([]byte(input), "yaml", "yaml", nil, nil, patch)
+	assert.NoError(t, err)
+	assert.Equal(t, "# first\nkind: Deployment\nreplicas: 3\n---\nkind: Service\nreplicas: 3\n---\nkind: Deployment\nreplicas: 3\n", string(out))
+
+	filter, err := operations.NewParser().ParsePredicate(`kind == "Deployment"`)
+	assert.NoError(t, err)
+
+	out, err = Transform([]byte(input), "yaml", "yaml", []int{0, 1}, filter, patch)
+	assert.NoError(t, err)
+	assert.Equal(t, "# first\nkind: Deployment\nreplicas: 3\n---\nkind: Service\n---\nkind: Deployment\n", string(out))
+
+	_, err = Transform([]byte(input), "yaml", "yaml", []int{3}, nil, patch)
+	assert.Error(t, err)
+
+	ops, err = jsonpatch.Parse([]byte(`[{ "op": "replace", "path": "/use/x", "value": 7 }]`))
+	assert.NoError(t, err)
+
+	out, err = Transform([]byte("base: &base\n  x: 1\nuse: *base\n"), "yaml", "yaml", nil, nil, patch)
+	assert.NoError(t, err)
+	assert.Equal(t, "base: &base\n  x: 1\nuse:\n  x: 7\n", string(out))
+
+	out, err = Transform([]byte(input), "yaml", "yaml", []int{2}, nil, func(_ int, doc types.Node) error {
+		return operations.MergePatch(doc, nil, map[string]any{"kind": "StatefulSet", "replicas": nil})
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "# first\nkind: Deployment\nreplicas: 1\n---\nkind: Service\n---\nkind: StatefulSet\n", string(out))
+}
+
+func TestApplyToValue(t *testing.T) {
+	var value any
+
+	assert.NoError(t, json.Unmarshal([]byte(`{ "a": { "b": [ 1, 2 ] } }`), &value))
+
+	out, err := ApplyToValue(value, `push(a.b, 3)`, `set(/a/c, "x")`)
+	assert.NoError(t, err)
+
+	assert.Equal[any](t, map[string]any{"a": map[string]any{"b": []any{1.0, 2.0, 3.0}, "c": "x"}}, out)
+	assert.Equal[any](t, map[string]any{"a": map[string]any{"b": []any{1.0, 2.0}}}, value)
+
+	dec := json.NewDecoder(strings.NewReader(`{ "a": [ 1 ] }`))
+	dec.UseNumber()
+
+	var numbers any
+
+	assert.NoError(t, dec.Decode(&numbers))
+
+	out, err = ApplyToValue(numbers, `push(a, 2.50)`, `set(b, { "c": 1 })`)
+	assert.NoError(t, err)
+
+	assert.Equal[any](t, map[string]any{"a": []any{json.Number("1"), json.Number("2.50")}, "b": map[string]any{"c": json.Number("1")}}, out)
+
+	_, err = ApplyToValue(value, `pop(a.c)`)
+	assert.Error(t, err)
+}
+
+func TestWithRegistry(t *testing.T) {
+	r := operations.DefaultRegistry()
+
+	r.MustRegister("bumpversion", func(root types.Node, path []types.PathElement, args ...any) error {
+		return operations.Set(root, path, "2.0.0")
+	}, operations.Signature{Help: "bump the major version"})
+
+	prog, err := Compile([]string{`bumpversion(version)`}, WithRegistry(r))
+	assert.NoError(t, err)
+
+	out, err := prog.Apply([]byte(`{"version": "1.0.0"}`), "json", "json")
+	assert.NoError(t, err)
+	assert.Equal(t, "{\n  \"version\": \"2.0.0\"\n}", string(out))
+
+	_, err = Compile([]string{`bumpversion(version)`})
+	assert.Error(t, err)
+}
+
+func TestContinueOnError(t *testing.T) {
+	input := "a: 1\nb: [1]\n---\na: [2]\n"
+	exprs := []string{`push(b, 2); pop(a); set(c, true)`}
+
+	prog, err := Compile(exprs)
+	assert.NoError(t, err)
+
+	_, err = prog.Apply([]byte(input), "yaml", "yaml")
+	assert.Error(t, err)
+
+	prog, err = Compile(exprs, WithContinueOnError())
+	assert.NoError(t, err)
+
+	out, err := prog.Apply([]byte(input), "yaml", "yaml")
+	assert.Equal(t, "a: 1\nb: [1, 2]\nc: true\n---\na: []\nc: true\n", string(out))
+
+	var skipped *SkippedError
+	assert.True(t, errors.As(err, &skipped))
+	assert.Equal(t, 2, len(skipped.Failures))
+	assert.Equal(t, 0, skipped.Failures[0].Doc)
+	assert.Equal(t, 1, skipped.Failures[0].Idx)
+	assert.Equal(t, 1, skipped.Failures[1].Doc)
+	assert.Equal(t, 0, skipped.Failures[1].Idx)
+}
@@ -0,0 +1,543 @@
+// Package orderedobject implements types.Node on top of objects
+// that remember the order of their keys.
+//
+// The node speaks plain go values to the outside world, the same ones
+// simpleobject is using, hence operations do not need to know anything
+// about ordering. Whenever a plain value is assigned to an existing
+// field it's merged into the existing structure, keys present
+// in both keep their positions and new keys are appended at the end.
+package orderedobject
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+
+	"github.com/can3p/sackmesser/pkg/traverse/types"
+)
+
+// Object is a json object that keeps track of the keys order
+type Object struct {
+	keys   []string
+	values map[string]any
+}
+
+func NewObject() *Object {
+	return &Object{
+		values: map[string]any{},
+	}
+}
+
+func (o *Object) Keys() []string {
+	return o.keys
+}
+
+func (o *Object) Get(key string) (any, bool) {
+	v, ok := o.values[key]
+	return v, ok
+}
+
+// Set updates the value in place if the key exists,
+// otherwise it's appended to the end of the object
+func (o *Object) Set(key string, value any) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+
+	o.values[key] = value
+}
+
+func (o *Object) Delete(key string) {
+	if _, ok := o.values[key]; !ok {
+		return
+	}
+
+	delete(o.values, key)
+
+	for idx, k := range o.keys {
+		if k == key {
+			o.keys = append(o.keys[:idx], o.keys[idx+1:]...)
+			break
+		}
+	}
+}
+
+func (o *Object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+
+	buf.WriteByte('{')
+
+	for idx, key := range o.keys {
+		if idx > 0 {
+			buf.WriteByte(',')
+		}
+
+		k, err := marshal(key)
+
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(k)
+		buf.WriteByte(':')
+
+		v, err := marshal(o.values[key])
+
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(v)
+	}
+
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// Array is a pointer type to make it possible
+// to modify arrays without a reference to the parent
+type Array struct {
+	Items []any
+}
+
+func (a *Array) MarshalJSON() ([]byte, error) {
+	// nil slice would be serialized as null otherwise
+	if a.Items == nil {
+		return []byte("[]"), nil
+	}
+
+	return marshal(a.Items)
+}
+
+// marshal does not escape html characters, the caller
+// decides on that, since json.Marshal will escape the output
+// of MarshalJSON anyway if asked to
+func marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+type onode struct {
+	v any
+}
+
+func (n *onode) Visit(field types.PathElement) (types.Node, error) {
+	val, err := n.child(field)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &onode{v: val}, nil
+}
+
+func (n *onode) child(field types.PathElement) (any, error) {
+	switch typed := n.v.(type) {
+	case *Object:
+		if !field.IsField() {
+			return nil, types.ErrWrongVisit
+		}
+
+		val, ok := typed.Get(field.ObjectField)
+
+		if !ok {
+			return nil, types.ErrFieldMissing
+		}
+
+		return val, nil
+	case *Array:
+		idx, err := field.Idx(len(typed.Items))
+
+		if err != nil {
+			return nil, err
+		}
+
+		return typed.Items[idx], nil
+	}
+
+	return nil, types.ErrWrongVisit
+}
+
+func (n *onode) GetField(field types.PathElement) (any, error) {
+	val, err := n.child(field)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return toPlain(val), nil
+}
+
+func (n *onode) SetField(field types.PathElement, value any) error {
+	switch typed := n.v.(type) {
+	case *Object:
+		if !field.IsField() {
+			return types.ErrWrongVisit
+		}
+
+		existing, ok := typed.Get(field.ObjectField)
+
+		if !ok {
+			typed.Set(field.ObjectField, FromPlain(value))
+			return nil
+		}
+
+		typed.Set(field.ObjectField, reconcile(existing, value))
+
+		return nil
+	case *Array:
+		idx, err := field.Idx(len(typed.Items))
+
+		if err != nil {
+			return err
+		}
+
+		typed.Items[idx] = reconcile(typed.Items[idx], value)
+
+		return nil
+	}
+
+	return types.ErrWrongVisit
+}
+
+func (n *onode) DeleteField(field types.PathElement) error {
+	switch typed := n.v.(type) {
+	case *Object:
+		if !field.IsField() {
+			return types.ErrWrongVisit
+		}
+
+		typed.Delete(field.ObjectField)
+
+		return nil
+	case *Array:
+		idx, err := field.Idx(len(typed.Items))
+
+		if err != nil {
+			return err
+		}
+
+		typed.Items = append(typed.Items[:idx], typed.Items[idx+1:]...)
+
+		return nil
+	}
+
+	return types.ErrWrongVisit
+}
+
+func (n *onode) InsertField(field types.PathElement, value any) error {
+	typed, ok := n.v.(*Array)
+
+	if !ok {
+		return types.ErrWrongVisit
+	}
+
+	idx, err := types.ResolveInsertIdx(field.ArrayIdx, len(typed.Items))
+
+	if err != nil {
+		return err
+	}
+
+	typed.Items = append(typed.Items[:idx], append([]any{FromPlain(value)}, typed.Items[idx:]...)...)
+
+	return nil
+}
+
+func (n *onode) Fields() []types.PathElement {
+	out := []types.PathElement{}
+
+	switch typed := n.v.(type) {
+	case *Object:
+		for _, key := range typed.keys {
+			out = append(out, types.Field(key))
+		}
+	case *Array:
+		for idx := range typed.Items {
+			out = append(out, types.PathElement{ArrayIdx: idx})
+		}
+	}
+
+	return out
+}
+
+// Snapshot saves the contents of every object and array, they are
+// restored in place, hence references to them stay valid
+func (n *onode) Snapshot() func() {
+	v := n.v
+	objects := map[*Object]Object{}
+	arrays := map[*Array]Array{}
+
+	saveValue(v, objects, arrays)
+
+	return func() {
+		n.v = v
+
+		for ptr, obj := range objects {
+			*ptr = obj
+		}
+
+		for ptr, arr := range arrays {
+			*ptr = arr
+		}
+	}
+}
+
+func saveValue(v any, objects map[*Object]Object, arrays map[*Array]Array) {
+	switch typed := v.(type) {
+	case *Object:
+		values := make(map[string]any, len(typed.values))
+
+		for key, value := range typed.values {
+			values[key] = value
+			saveValue(value, objects, arrays)
+		}
+
+		objects[typed] = Object{
+			keys:   append([]string{}, typed.keys...),
+			values: values,
+		}
+	case *Array:
+		arrays[typed] = Array{Items: append([]any{}, typed.Items...)}
+
+		for _, item := range typed.Items {
+			saveValue(item, objects, arrays)
+		}
+	}
+}
+
+func (n *onode) Value() any {
+	return toPlain(n.v)
+}
+
+func (n *onode) NodeType() types.NodeType {
+	switch n.v.(type) {
+	case nil:
+		return types.NodeTypeNull
+	case bool:
+		return types.NodeTypeBool
+	case json.Number, float64, float32, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return types.NodeTypeNumber
+	case string:
+		return types.NodeTypeString
+	case *Object:
+		return types.NodeTypeObject
+	case *Array:
+		return types.NodeTypeArray
+	}
+
+	panic("unreachable")
+}
+
+func toPlain(v any) any {
+	switch typed := v.(type) {
+	case *Object:
+		out := make(map[string]any, len(typed.keys))
+
+		for _, key := range typed.keys {
+			out[key] = toPlain(typed.values[key])
+		}
+
+		return out
+	case *Array:
+		out := make([]any, 0, len(typed.Items))
+
+		for _, item := range typed.Items {
+			out = append(out, toPlain(item))
+		}
+
+		return out
+	}
+
+	return v
+}
+
+// FromPlain converts plain go values into ordered ones,
+// keys of maps are sorted to make the result stable
+func FromPlain(v any) any {
+	switch typed := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(typed))
+
+		for key := range typed {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		out := NewObject()
+
+		for _, key := range keys {
+			out.Set(key, FromPlain(typed[key]))
+		}
+
+		return out
+	case []any:
+		out := &Array{Items: make([]any, 0, len(typed))}
+
+		for _, item := range typed {
+			out.Items = append(out.Items, FromPlain(item))
+		}
+
+		return out
+	}
+
+	return v
+}
+
+// reconcile merges plain value into the existing ordered one
+func reconcile(existing any, value any) any {
+	switch typed := value.(type) {
+	case map[string]any:
+		obj, ok := existing.(*Object)
+
+		if !ok {
+			break
+		}
+
+		for _, key := range append([]string{}, obj.keys...) {
+			if _, ok := typed[key]; !ok {
+				obj.Delete(key)
+			}
+		}
+
+		newKeys := []string{}
+
+		for key, v := range typed {
+			current, ok := obj.Get(key)
+
+			if !ok {
+				newKeys = append(newKeys, key)
+				continue
+			}
+
+			obj.Set(key, reconcile(current, v))
+		}
+
+		sort.Strings(newKeys)
+
+		for _, key := range newKeys {
+			obj.Set(key, FromPlain(typed[key]))
+		}
+
+		return obj
+	case []any:
+		arr, ok := existing.(*Array)
+
+		if !ok {
+			break
+		}
+
+		if len(typed) < len(arr.Items) {
+			arr.Items = arr.Items[:len(typed)]
+		}
+
+		for idx, v := range typed {
+			if idx < len(arr.Items) {
+				arr.Items[idx] = reconcile(arr.Items[idx], v)
+				continue
+			}
+
+			arr.Items = append(arr.Items, FromPlain(v))
+		}
+
+		return arr
+	}
+
+	return FromPlain(value)
+}
+
+// Ordered returns the value of the node with objects and arrays
+// represented as *Object and *Array. The value is returned as is
+// for ordered nodes, any other node is converted
+func Ordered(n types.Node) any {
+	if typed, ok := n.(*onode); ok {
+		return typed.v
+	}
+
+	return FromPlain(n.Value())
+}
+
+// FromValue wraps a value that can contain both plain
+// and ordered objects and arrays
+func FromValue(v any) types.Node {
+	return &onode{
+		v: FromPlain(v),
+	}
+}
+
+func FromNode(n types.Node) types.Node {
+	return &onode{
+		v: Ordered(n),
+	}
+}
+
+// Decode reads a single json value from the decoder
+// preserving the order of object keys
+func Decode(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		obj := NewObject()
+
+		for dec.More() {
+			keyTok, err := dec.Token()
+
+			if err != nil {
+				return nil, err
+			}
+
+			// decoder guarantees that keys are strings
+			key := keyTok.(string)
+
+			val, err := Decode(dec)
+
+			if err != nil {
+				return nil, err
+			}
+
+			obj.Set(key, val)
+		}
+
+		// closing brace
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+
+		return obj, nil
+	case json.Delim('['):
+		arr := &Array{Items: []any{}}
+
+		for dec.More() {
+			val, err := Decode(dec)
+
+			if err != nil {
+				return nil, err
+			}
+
+			arr.Items = append(arr.Items, val)
+		}
+
+		// closing bracket
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+
+		return arr, nil
+	}
+
+	return tok, nil
+}
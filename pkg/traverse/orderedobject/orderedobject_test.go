@@ -0,0 +1,176 @@
+package orderedobject
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/can3p/sackmesser/pkg/traverse/types"
+)
+
+func parse(t *testing.T, s string) types.Node {
+	t.Helper()
+
+	dec := json.NewDecoder(bytes.NewReader([]byte(s)))
+	dec.UseNumber()
+
+	v, err := Decode(dec)
+	assert.NoError(t, err)
+
+	return FromValue(v)
+}
+
+func serialize(t *testing.T, n types.Node) string {
+	t.Helper()
+
+	b, err := json.Marshal(Ordered(n))
+	assert.NoError(t, err)
+
+	return string(b)
+}
+
+func TestKeyOrder(t *testing.T) {
+	jstr := `{"z":1,"a":{"y":2,"b":[{"k":1,"c":2}]},"m":null}`
+
+	examples := []struct {
+		description string
+		path        []types.PathElement
+		value       any
+		del         bool
+		expected    string
+		isErr       bool
+	}{
+		{
+			description: "untouched document keeps the order",
+			path:        []types.PathElement{{ObjectField: "m"}},
+			value:       nil,
+			expected:    jstr,
+		},
+		{
+			description: "new key is appended",
+			path:        []types.PathElement{{ObjectField: "a"}, {ObjectField: "new"}},
+			value:       true,
+			expected:    `{"z":1,"a":{"y":2,"b":[{"k":1,"c":2}],"new":true},"m":null}`,
+		},
+		{
+			description: "existing key keeps its position",
+			path:        []types.PathElement{{ObjectField: "z"}},
+			value:       "updated",
+			expected:    `{"z":"updated","a":{"y":2,"b":[{"k":1,"c":2}]},"m":null}`,
+		},
+		{
+			description: "plain object is merged into the existing one",
+			path:        []types.PathElement{{ObjectField: "a"}, {ObjectField: "b"}, {ArrayIdx: 0}},
+			value:       map[string]any{"c": 3, "k": 1, "b": true, "a": false},
+			expected:    `{"z":1,"a":{"y":2,"b":[{"k":1,"c":3,"a":false,"b":true}]},"m":null}`,
+		},
+		{
+			description: "keys missing in the new value are removed",
+			path:        []types.PathElement{{ObjectField: "a"}},
+			value:       map[string]any{"b": []any{}},
+			expected:    `{"z":1,"a":{"b":[]},"m":null}`,
+		},
+		{
+			description: "delete key",
+			path:        []types.PathElement{{ObjectField: "a"}, {ObjectField: "y"}},
+			del:         true,
+			expected:    `{"z":1,"a":{"b":[{"k":1,"c":2}]},"m":null}`,
+		},
+		{
+			description: "delete array item",
+			path:        []types.PathElement{{ObjectField: "a"}, {ObjectField: "b"}, {ArrayIdx: 0}},
+			del:         true,
+			expected:    `{"z":1,"a":{"y":2,"b":[]},"m":null}`,
+		},
+		{
+			description: "negative index counts from the end",
+			path:        []types.PathElement{{ObjectField: "a"}, {ObjectField: "b"}, {ArrayIdx: -1}, {ObjectField: "k"}},
+			value:       2,
+			expected:    `{"z":1,"a":{"y":2,"b":[{"k":2,"c":2}]},"m":null}`,
+		},
+		{
+			description: "array index out of bounds",
+			path:        []types.PathElement{{ObjectField: "a"}, {ObjectField: "b"}, {ArrayIdx: 1}},
+			value:       true,
+			isErr:       true,
+		},
+	}
+
+	for idx, ex := range examples {
+		node := parse(t, jstr)
+		root := node
+		var err error
+
+		for _, p := range ex.path[:len(ex.path)-1] {
+			node, err = node.Visit(p)
+			assert.NoError(t, err, "[Ex %d - %s]", idx+1, ex.description)
+		}
+
+		if ex.del {
+			err = node.DeleteField(ex.path[len(ex.path)-1])
+		} else {
+			err = node.SetField(ex.path[len(ex.path)-1], ex.value)
+		}
+
+		if ex.isErr {
+			assert.Error(t, err, "[Ex %d - %s]", idx+1, ex.description)
+			continue
+		} else {
+			assert.NoError(t, err, "[Ex %d - %s]", idx+1, ex.description)
+		}
+
+		assert.Equal(t, ex.expected, serialize(t, root), "[Ex %d - %s]", idx+1, ex.description)
+	}
+}
+
+func TestPlainValues(t *testing.T) {
+	node := parse(t, `{"b":[1,{"c":"d"}],"a":true}`)
+
+	assert.Equal[any](t, map[string]any{
+		"a": true,
+		"b": []any{json.Number("1"), map[string]any{"c": "d"}},
+	}, node.Value())
+
+	val, err := node.GetField(types.PathElement{ObjectField: "b"})
+	assert.NoError(t, err)
+	assert.Equal[any](t, []any{json.Number("1"), map[string]any{"c": "d"}}, val)
+
+	child, err := node.Visit(types.PathElement{ObjectField: "b"})
+	assert.NoError(t, err)
+	assert.Equal(t, types.NodeTypeArray, child.NodeType())
+}
+
+func TestSnapshot(t *testing.T) {
+	initial := `{"b":1,"a":{"c":[1,2]}}`
+
+	root := parse(t, initial)
+	restore := root.Snapshot()
+
+	assert.NoError(t, root.SetField(types.PathElement{ObjectField: "d"}, 1))
+	assert.NoError(t, root.DeleteField(types.PathElement{ObjectField: "b"}))
+
+	a, err := root.Visit(types.PathElement{ObjectField: "a"})
+	assert.NoError(t, err)
+	c, err := a.Visit(types.PathElement{ObjectField: "c"})
+	assert.NoError(t, err)
+	assert.NoError(t, c.DeleteField(types.PathElement{ArrayIdx: 0}))
+
+	restore()
+
+	assert.Equal(t, initial, serialize(t, root))
+}
+
+func TestInsertField(t *testing.T) {
+	root := parse(t, `{"a":[1,2]}`)
+
+	a, err := root.Visit(types.PathElement{ObjectField: "a"})
+	assert.NoError(t, err)
+
+	assert.NoError(t, a.InsertField(types.PathElement{ArrayIdx: 0}, map[string]any{"b": 0}))
+	assert.NoError(t, a.InsertField(types.PathElement{ArrayIdx: 3}, 3))
+	assert.Error(t, a.InsertField(types.PathElement{ArrayIdx: 5}, 5))
+	assert.Error(t, root.InsertField(types.PathElement{ArrayIdx: 0}, 0))
+
+	assert.Equal(t, `{"a":[{"b":0},1,2,3]}`, serialize(t, root))
+}
@@ -1,10 +1,14 @@
 package simplejson
 
 import (
+	"bytes"
 	"encoding/json"
+	"io"
 
+	"github.com/can3p/sackmesser/pkg/traverse/orderedobject"
 	"github.com/can3p/sackmesser/pkg/traverse/simpleobject"
 	"github.com/can3p/sackmesser/pkg/traverse/types"
+	"github.com/pkg/errors"
 )
 
 type jnode struct {
@@ -12,16 +16,42 @@ type jnode struct {
 }
 
 func (n *jnode) Serialize() ([]byte, error) {
-	return json.MarshalIndent(n.Value(), "", "  ")
+	return marshalIndent(n.Value())
+}
+
+// marshalIndent does not escape html characters
+// to keep the untouched strings intact
+func marshalIndent(v any) ([]byte, error) {
+	var buf bytes.Buffer
+
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
 }
 
 func Parse(b []byte) (types.RootNode, error) {
 	var j any
 
-	if err := json.Unmarshal(b, &j); err != nil {
+	// numbers are kept as json.Number to avoid
+	// any precision loss and to make sure that
+	// untouched numbers are serialized exactly the same way
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	if err := dec.Decode(&j); err != nil {
 		return nil, err
 	}
 
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.Errorf("invalid character after top-level value")
+	}
+
 	return &jnode{
 		simpleobject.FromValue(j),
 	}, nil
@@ -42,3 +72,71 @@ func FromNode(n types.Node) types.RootNode {
 		simpleobject.FromNode(n),
 	}
 }
+
+// orderedNode keeps the order of object keys the way
+// it was in the original document, new keys are appended to the end
+type orderedNode struct {
+	types.Node
+}
+
+func (n *orderedNode) Serialize() ([]byte, error) {
+	return marshalIndent(orderedobject.Ordered(n.Node))
+}
+
+func ParseOrdered(b []byte) (types.RootNode, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	j, err := orderedobject.Decode(dec)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.Errorf("invalid character after top-level value")
+	}
+
+	return &orderedNode{
+		orderedobject.FromValue(j),
+	}, nil
+}
+
+func MustParseOrdered(b []byte) types.RootNode {
+	n, err := ParseOrdered(b)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return n
+}
+
+func FromNodeOrdered(n types.Node) types.RootNode {
+	if typed, ok := n.(*orderedNode); ok {
+		return typed
+	}
+
+	return &orderedNode{
+		orderedobject.FromNode(n),
+	}
+}
+
+// Compact serializes the node into a single line,
+// the order of keys is kept for ordered nodes
+func Compact(n types.Node) ([]byte, error) {
+	if typed, ok := n.(*orderedNode); ok {
+		n = typed.Node
+	}
+
+	var buf bytes.Buffer
+
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+
+	if err := enc.Encode(orderedobject.Ordered(n)); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
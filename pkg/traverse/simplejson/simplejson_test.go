@@ -0,0 +1,41 @@
+package simplejson
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/can3p/sackmesser/pkg/traverse/types"
+)
+
+func TestSerialize(t *testing.T) {
+	examples := []struct {
+		description string
+		parse       func(b []byte) (types.RootNode, error)
+	}{
+		{
+			description: "plain",
+			parse:       Parse,
+		},
+		{
+			description: "ordered",
+			parse:       ParseOrdered,
+		},
+	}
+
+	input := `{ "html": "<a href=\"/?a=1&b=2\">link</a>", "num": 1.10 }`
+
+	for idx, ex := range examples {
+		root, err := ex.parse([]byte(input))
+		assert.NoError(t, err, "[Ex %d - %s]", idx+1, ex.description)
+
+		out, err := root.Serialize()
+		assert.NoError(t, err, "[Ex %d - %s]", idx+1, ex.description)
+
+		assert.Equal(t, "{\n  \"html\": \"<a href=\\\"/?a=1&b=2\\\">link</a>\",\n  \"num\": 1.10\n}", string(out), "[Ex %d - %s]", idx+1, ex.description)
+
+		out, err = Compact(root)
+		assert.NoError(t, err, "[Ex %d - %s]", idx+1, ex.description)
+
+		assert.Equal(t, `{"html":"<a href=\"/?a=1&b=2\">link</a>","num":1.10}`, string(out), "[Ex %d - %s]", idx+1, ex.description)
+	}
+}
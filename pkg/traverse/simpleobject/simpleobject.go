@@ -1,9 +1,11 @@
 package simpleobject
 
 import (
+	"encoding"
+	"encoding/json"
 	"fmt"
 	"reflect"
-	"strconv"
+	"sort"
 
 	"github.com/can3p/sackmesser/pkg/traverse/types"
 )
@@ -21,8 +23,6 @@ type jnode struct {
 // nodes whenever you've done any modifications to
 // the parent node
 func (n *jnode) Visit(field types.PathElement) (types.Node, error) {
-	var err error
-
 	switch n.NodeType() {
 	case types.NodeTypeNull:
 		fallthrough
@@ -35,7 +35,7 @@ func (n *jnode) Visit(field types.PathElement) (types.Node, error) {
 	case types.NodeTypeObject:
 		m := n.v.(map[string]any)
 
-		if field.ObjectField == "" {
+		if !field.IsField() {
 			return nil, types.ErrWrongVisit
 		}
 
@@ -54,21 +54,10 @@ func (n *jnode) Visit(field types.PathElement) (types.Node, error) {
 	case types.NodeTypeArray:
 		m := n.v.([]any)
 
-		var idx int64
-
-		if field.ObjectField == "" {
-			idx = int64(field.ArrayIdx)
-		} else {
-
-			idx, err = strconv.ParseInt(field.ObjectField, 10, 64)
+		idx, err := field.Idx(len(m))
 
-			if err != nil {
-				return nil, err
-			}
-		}
-
-		if idx < 0 || idx >= int64(len(m)) {
-			return nil, types.ErrIdxOutOfBounds
+		if err != nil {
+			return nil, err
 		}
 
 		val := m[idx]
@@ -85,31 +74,19 @@ func (n *jnode) Visit(field types.PathElement) (types.Node, error) {
 }
 
 func (n *jnode) GetField(field types.PathElement) (any, error) {
-	var err error
 	typedArr, ok := n.v.([]any)
 
 	if ok {
-		var idx int64
-
-		if field.ObjectField == "" {
-			idx = int64(field.ArrayIdx)
-		} else {
-
-			idx, err = strconv.ParseInt(field.ObjectField, 10, 64)
-
-			if err != nil {
-				return nil, err
-			}
-		}
+		idx, err := field.Idx(len(typedArr))
 
-		if idx < 0 || idx >= int64(len(typedArr)) {
-			return nil, types.ErrIdxOutOfBounds
+		if err != nil {
+			return nil, err
 		}
 
 		return typedArr[idx], nil
 	}
 
-	if field.ObjectField == "" {
+	if !field.IsField() {
 		return nil, types.ErrWrongVisit
 	}
 
@@ -137,7 +114,7 @@ func (n *jnode) SetField(field types.PathElement, value any) error {
 	case types.NodeTypeBool:
 		return types.ErrWrongVisit
 	case types.NodeTypeObject:
-		if field.ObjectField == "" {
+		if !field.IsField() {
 			return types.ErrWrongVisit
 		}
 
@@ -149,22 +126,10 @@ func (n *jnode) SetField(field types.PathElement, value any) error {
 	case types.NodeTypeArray:
 		m := n.v.([]any)
 
-		var idx int64
-		var err error
-
-		if field.ObjectField == "" {
-			idx = int64(field.ArrayIdx)
-		} else {
+		idx, err := field.Idx(len(m))
 
-			idx, err = strconv.ParseInt(field.ObjectField, 10, 64)
-
-			if err != nil {
-				return err
-			}
-		}
-
-		if idx < 0 || idx >= int64(len(m)) {
-			return types.ErrIdxOutOfBounds
+		if err != nil {
+			return err
 		}
 
 		m[idx] = value
@@ -188,7 +153,7 @@ func (n *jnode) DeleteField(field types.PathElement) error {
 	case types.NodeTypeObject:
 		m := n.v.(map[string]any)
 
-		if field.ObjectField == "" {
+		if !field.IsField() {
 			return types.ErrWrongVisit
 		}
 
@@ -203,22 +168,10 @@ func (n *jnode) DeleteField(field types.PathElement) error {
 	case types.NodeTypeArray:
 		m := n.v.([]any)
 
-		var idx int64
-		var err error
-
-		if field.ObjectField == "" {
-			idx = int64(field.ArrayIdx)
-		} else {
-
-			idx, err = strconv.ParseInt(field.ObjectField, 10, 64)
+		idx, err := field.Idx(len(m))
 
-			if err != nil {
-				return err
-			}
-		}
-
-		if idx < 0 || idx >= int64(len(m)) {
-			return types.ErrIdxOutOfBounds
+		if err != nil {
+			return err
 		}
 
 		// in case of array splice, just mutating the current node
@@ -227,12 +180,105 @@ func (n *jnode) DeleteField(field types.PathElement) error {
 		// The way to cure that was to modify the array and ask
 		// the parent to replace it completely with updated value
 		copy(m[idx:], m[idx+1:])
-		return n.parent.SetField(n.accessedField, m[:len(m)-1])
+		n.v = m[:len(m)-1]
+
+		// root array has no parent to update
+		if n.parent == nil {
+			return nil
+		}
+
+		return n.parent.SetField(n.accessedField, n.v)
 	}
 
 	panic("unreachable")
 }
 
+func (n *jnode) InsertField(field types.PathElement, value any) error {
+	m, ok := n.v.([]any)
+
+	if !ok {
+		return types.ErrWrongVisit
+	}
+
+	idx, err := types.ResolveInsertIdx(field.ArrayIdx, len(m))
+
+	if err != nil {
+		return err
+	}
+
+	updated := make([]any, 0, len(m)+1)
+	updated = append(updated, m[:idx]...)
+	updated = append(updated, value)
+	updated = append(updated, m[idx:]...)
+
+	// same as with DeleteField, the parent has to get the updated slice
+	n.v = updated
+
+	if n.parent == nil {
+		return nil
+	}
+
+	return n.parent.SetField(n.accessedField, n.v)
+}
+
+// keys of plain maps have no order, they are sorted
+// to keep the result stable
+func (n *jnode) Fields() []types.PathElement {
+	out := []types.PathElement{}
+
+	switch typed := n.v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(typed))
+
+		for key := range typed {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			out = append(out, types.Field(key))
+		}
+	case []any:
+		for idx := range typed {
+			out = append(out, types.PathElement{ArrayIdx: idx})
+		}
+	}
+
+	return out
+}
+
+// Snapshot keeps a deep copy of the value. Objects are restored in place
+// since the parent and the callers keep references to them, anything else
+// is put back into the parent the same way DeleteField does it
+func (n *jnode) Snapshot() func() {
+	saved := types.DeepCopy(n.v)
+
+	return func() {
+		current, isMap := n.v.(map[string]any)
+		savedMap, wasMap := saved.(map[string]any)
+
+		if isMap && wasMap {
+			for key := range current {
+				delete(current, key)
+			}
+
+			for key, value := range savedMap {
+				current[key] = value
+			}
+
+			return
+		}
+
+		n.v = saved
+		n.vType = reflect.TypeOf(saved)
+
+		if n.parent != nil {
+			_ = n.parent.SetField(n.accessedField, saved)
+		}
+	}
+}
+
 func (n *jnode) Value() any {
 	return n.v
 }
@@ -249,6 +295,17 @@ func (n *jnode) NodeType() types.NodeType {
 		return types.NodeTypeNull
 	}
 
+	// json.Number is a string under the hood
+	if _, ok := n.v.(json.Number); ok {
+		return types.NodeTypeNumber
+	}
+
+	// dates and times are strings for all the formats that
+	// do not support them natively
+	if _, ok := n.v.(encoding.TextMarshaler); ok {
+		return types.NodeTypeString
+	}
+
 	switch n.vType.Kind() {
 	case reflect.Bool:
 		return types.NodeTypeBool
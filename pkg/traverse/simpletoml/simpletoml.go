@@ -0,0 +1,133 @@
+package simpletoml
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/pkg/errors"
+
+	"github.com/can3p/sackmesser/pkg/traverse/simpleobject"
+	"github.com/can3p/sackmesser/pkg/traverse/types"
+)
+
+// Datetimes are kept as they are parsed: offset datetimes
+// are time.Time, local dates, times and datetimes use
+// corresponding toml types. All of them are serialized back
+// into the same toml type and they are strings for any other format
+type jnode struct {
+	types.Node
+}
+
+func (n *jnode) Serialize() ([]byte, error) {
+	v, err := prepare(n.Value(), types.PathElementSlice{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := v.(map[string]any); !ok {
+		return nil, errors.Errorf("toml document should be an object, got %s", n.NodeType())
+	}
+
+	return toml.Marshal(v)
+}
+
+func Parse(b []byte) (types.RootNode, error) {
+	var j map[string]any
+
+	if err := toml.Unmarshal(b, &j); err != nil {
+		return nil, err
+	}
+
+	// empty document
+	if j == nil {
+		j = map[string]any{}
+	}
+
+	return &jnode{
+		simpleobject.FromValue(j),
+	}, nil
+}
+
+func MustParse(b []byte) types.Node {
+	n, err := Parse(b)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return n
+}
+
+func FromNode(n types.Node) types.RootNode {
+	return &jnode{
+		simpleobject.FromNode(n),
+	}
+}
+
+// prepare makes sure that the value can be represented in toml,
+// there is no null in toml and json numbers have to become real ones
+func prepare(v any, path types.PathElementSlice) (any, error) {
+	switch typed := v.(type) {
+	case nil:
+		return nil, errors.Errorf("toml does not support null values, found one at [%s]", path.String())
+	case json.Number:
+		if i, err := typed.Int64(); err == nil {
+			return i, nil
+		}
+
+		// toml integers are 64 bit, a float would silently lose the precision
+		if !strings.ContainsAny(typed.String(), ".eE") {
+			return nil, errors.Errorf("toml integers are 64 bit, %s at [%s] does not fit", typed.String(), path.String())
+		}
+
+		f, err := strconv.ParseFloat(typed.String(), 64)
+
+		if err != nil {
+			return nil, errors.Errorf("toml floats are 64 bit, %s at [%s] does not fit", typed.String(), path.String())
+		}
+
+		return f, nil
+	case map[string]any:
+		out := make(map[string]any, len(typed))
+
+		for key, value := range typed {
+			prepared, err := prepare(value, appendPath(path, types.Field(key)))
+
+			if err != nil {
+				return nil, err
+			}
+
+			out[key] = prepared
+		}
+
+		return out, nil
+	case []any:
+		out := make([]any, 0, len(typed))
+
+		for idx, value := range typed {
+			prepared, err := prepare(value, appendPath(path, types.PathElement{ArrayIdx: idx}))
+
+			if err != nil {
+				return nil, err
+			}
+
+			out = append(out, prepared)
+		}
+
+		return out, nil
+	}
+
+	return v, nil
+}
+
+// appendPath does not reuse the backing array of the path,
+// otherwise siblings would overwrite each other's elements
+func appendPath(path types.PathElementSlice, el types.PathElement) types.PathElementSlice {
+	out := make(types.PathElementSlice, 0, len(path)+1)
+	out = append(out, path...)
+
+	return append(out, el)
+}
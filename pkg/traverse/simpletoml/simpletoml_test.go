@@ -0,0 +1,117 @@
+package simpletoml
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/can3p/sackmesser/pkg/traverse/simpleobject"
+	"github.com/can3p/sackmesser/pkg/traverse/types"
+)
+
+func TestRoundTrip(t *testing.T) {
+	input := `title = 'example'
+
+[dates]
+local_date = 1979-05-27
+local_datetime = 1979-05-27T07:32:00
+local_time = 07:32:00
+offset_datetime = 1979-05-27T07:32:00Z
+
+[[servers]]
+name = 'alpha'
+
+[[servers]]
+name = 'beta'
+`
+
+	root, err := Parse([]byte(input))
+	assert.NoError(t, err)
+
+	out, err := root.Serialize()
+	assert.NoError(t, err)
+
+	assert.Equal(t, input, string(out))
+
+	dates, err := root.Visit(types.PathElement{ObjectField: "dates"})
+	assert.NoError(t, err)
+
+	for _, field := range []string{"local_date", "local_datetime", "local_time", "offset_datetime"} {
+		node, err := dates.Visit(types.PathElement{ObjectField: field})
+		assert.NoError(t, err, field)
+		assert.Equal(t, types.NodeTypeString, node.NodeType(), field)
+	}
+
+	b, err := json.Marshal(dates.Value())
+	assert.NoError(t, err)
+	assert.Equal(t, `{"local_date":"1979-05-27","local_datetime":"1979-05-27T07:32:00","local_time":"07:32:00","offset_datetime":"1979-05-27T07:32:00Z"}`, string(b))
+}
+
+func TestSerialize(t *testing.T) {
+	examples := []struct {
+		description string
+		value       any
+		expected    string
+		isErr       bool
+		errMsg      string
+	}{
+		{
+			description: "json numbers become toml numbers",
+			value:       map[string]any{"int": json.Number("10"), "float": json.Number("1.5")},
+			expected:    "float = 1.5\nint = 10\n",
+		},
+		{
+			description: "nested objects become tables",
+			value:       map[string]any{"a": 1, "table": map[string]any{"b": true}},
+			expected:    "a = 1\n\n[table]\nb = true\n",
+		},
+		{
+			description: "null values are not supported",
+			value:       map[string]any{"a": []any{1, nil}},
+			isErr:       true,
+		},
+		{
+			description: "the error names the path of the null value",
+			value: map[string]any{"a": map[string]any{"b": map[string]any{
+				"c": []any{map[string]any{"d": 1, "e": 2}, map[string]any{"f": nil}},
+			}}},
+			isErr:  true,
+			errMsg: "toml does not support null values, found one at [a.b.c[1].f]",
+		},
+		{
+			description: "integers that do not fit into int64 are an error",
+			value:       map[string]any{"a": []any{json.Number("18446744073709551616")}},
+			isErr:       true,
+			errMsg:      "toml integers are 64 bit, 18446744073709551616 at [a[0]] does not fit",
+		},
+		{
+			description: "floats out of range are an error",
+			value:       map[string]any{"a": json.Number("1e400")},
+			isErr:       true,
+			errMsg:      "toml floats are 64 bit, 1e400 at [a] does not fit",
+		},
+		{
+			description: "top level value should be an object",
+			value:       []any{1, 2},
+			isErr:       true,
+		},
+	}
+
+	for idx, ex := range examples {
+		out, err := FromNode(simpleobject.FromValue(ex.value)).Serialize()
+
+		if ex.isErr {
+			assert.Error(t, err, "[Ex %d - %s]", idx+1, ex.description)
+
+			if ex.errMsg != "" {
+				assert.Equal(t, ex.errMsg, err.Error(), "[Ex %d - %s]", idx+1, ex.description)
+			}
+
+			continue
+		} else {
+			assert.NoError(t, err, "[Ex %d - %s]", idx+1, ex.description)
+		}
+
+		assert.Equal(t, ex.expected, string(out), "[Ex %d - %s]", idx+1, ex.description)
+	}
+}
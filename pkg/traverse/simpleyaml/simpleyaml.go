@@ -5,6 +5,7 @@ import (
 
 	"github.com/can3p/sackmesser/pkg/traverse/simpleobject"
 	"github.com/can3p/sackmesser/pkg/traverse/types"
+	"github.com/can3p/sackmesser/pkg/traverse/yamltree"
 )
 
 type jnode struct {
@@ -12,7 +13,13 @@ type jnode struct {
 }
 
 func (n *jnode) Serialize() ([]byte, error) {
-	return yaml.Marshal(n.Value())
+	node, err := yamltree.Encode(n.Value())
+
+	if err != nil {
+		return nil, err
+	}
+
+	return yaml.Marshal(node)
 }
 
 func Parse(b []byte) (types.Node, error) {
@@ -27,7 +34,13 @@ func Parse(b []byte) (types.Node, error) {
 	}, nil
 }
 
+// FromNode returns nodes of a yaml tree as is to keep
+// the formatting, any other node is converted
 func FromNode(n types.Node) types.RootNode {
+	if root, ok := yamltree.FromNode(n); ok {
+		return root
+	}
+
 	return &jnode{
 		simpleobject.FromNode(n),
 	}
@@ -0,0 +1,27 @@
+package types
+
+// DeepCopy copies plain maps and slices recursively,
+// it's used to avoid sharing the same value between
+// several places of a document
+func DeepCopy(v any) any {
+	switch typed := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(typed))
+
+		for key, value := range typed {
+			out[key] = DeepCopy(value)
+		}
+
+		return out
+	case []any:
+		out := make([]any, 0, len(typed))
+
+		for _, value := range typed {
+			out = append(out, DeepCopy(value))
+		}
+
+		return out
+	}
+
+	return v
+}
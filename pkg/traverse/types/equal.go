@@ -0,0 +1,82 @@
+package types
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+)
+
+// Equal compares plain values the way json does: numbers are
+// compared by value regardless of their go type, e.g. json.Number("1.0")
+// is equal to int(1)
+func Equal(a any, b any) bool {
+	if an, ok := ToNumber(a); ok {
+		bn, ok := ToNumber(b)
+		return ok && an.Cmp(bn) == 0
+	}
+
+	switch typed := a.(type) {
+	case map[string]any:
+		other, ok := b.(map[string]any)
+
+		if !ok || len(typed) != len(other) {
+			return false
+		}
+
+		for key, value := range typed {
+			otherValue, ok := other[key]
+
+			if !ok || !Equal(value, otherValue) {
+				return false
+			}
+		}
+
+		return true
+	case []any:
+		other, ok := b.([]any)
+
+		if !ok || len(typed) != len(other) {
+			return false
+		}
+
+		for idx := range typed {
+			if !Equal(typed[idx], other[idx]) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+// ToNumber converts any go number or json.Number into
+// an exact representation, false is returned if the value
+// is not a number
+func ToNumber(v any) (*big.Rat, bool) {
+	r := new(big.Rat)
+
+	switch typed := v.(type) {
+	case json.Number:
+		return r.SetString(typed.String())
+	case float64:
+		// nil is returned for infinities and NaN
+		r = r.SetFloat64(typed)
+		return r, r != nil
+	case float32:
+		r = r.SetFloat64(float64(typed))
+		return r, r != nil
+	}
+
+	rv := reflect.ValueOf(v)
+
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return r.SetInt64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return r.SetUint64(rv.Uint()), true
+	}
+
+	return nil, false
+}
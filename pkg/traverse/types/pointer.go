@@ -0,0 +1,50 @@
+package types
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+var pointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+
+// ParsePointer parses RFC 6901 JSON Pointer, e.g. /spec/containers/0/image.
+// Every reference token becomes an object field, fields that are RFC 6901
+// array indices are treated as such when they are applied to arrays.
+// Empty pointer points to the root, empty token is the empty key, e.g. /
+func ParsePointer(s string) (PathElementSlice, error) {
+	if s == "" {
+		return PathElementSlice{}, nil
+	}
+
+	if !strings.HasPrefix(s, "/") {
+		return nil, errors.Errorf("JSON Pointer should start with a slash: %s", s)
+	}
+
+	tokens := strings.Split(s[1:], "/")
+	out := make(PathElementSlice, 0, len(tokens))
+
+	for _, token := range tokens {
+		if strings.Contains(strings.ReplaceAll(strings.ReplaceAll(token, "~0", ""), "~1", ""), "~") {
+			return nil, errors.Errorf("invalid escape sequence in JSON Pointer: %s", s)
+		}
+
+		out = append(out, Field(pointerUnescaper.Replace(token)))
+	}
+
+	return out, nil
+}
+
+// Pointer formats the path as RFC 6901 JSON Pointer. Wildcards, filters and
+// the rest of the elements that have no pointer representation are written as is
+func (sl PathElementSlice) Pointer() string {
+	var buf strings.Builder
+
+	for _, p := range sl {
+		buf.WriteRune('/')
+		buf.WriteString(pointerEscaper.Replace(p.String()))
+	}
+
+	return buf.String()
+}
@@ -3,12 +3,15 @@ package types
 import (
 	"bytes"
 	"fmt"
+	"regexp"
+	"sort"
 	"strconv"
 )
 
 var ErrFieldMissing = fmt.Errorf("No field with such name")
 var ErrIdxOutOfBounds = fmt.Errorf("Index out of bounds")
 var ErrWrongVisit = fmt.Errorf("Not possible to visit a field for the value of this type")
+var ErrInvalidIdx = fmt.Errorf("Invalid array index")
 
 type NodeType string
 
@@ -23,17 +26,211 @@ var (
 
 type PathElement struct {
 	ObjectField string
-	ArrayIdx    int
+	// EmptyKey marks the object field with an empty name,
+	// since an empty ObjectField means an array index
+	EmptyKey bool
+	ArrayIdx int
+	// Wildcard matches every key of an object or every element of an array
+	Wildcard bool
+	// Recursive matches the node itself and all of its descendants
+	Recursive bool
+	// Filter matches every element of an array or every value
+	// of an object the predicate holds for
+	Filter Predicate
+	// Slice matches a range of array elements
+	Slice *Slice
+}
+
+// Field returns the path element for the object field,
+// the empty name is fine too
+func Field(key string) PathElement {
+	return PathElement{ObjectField: key, EmptyKey: key == ""}
+}
+
+// IsField reports whether the element is an object field
+func (pe PathElement) IsField() bool {
+	return pe.ObjectField != "" || pe.EmptyKey
+}
+
+// Idx returns the index of the array element the path element points to.
+// Object fields, e.g. the ones coming from JSON Pointers, are indices
+// only if they are written the RFC 6901 way, see ParseArrayIdx
+func (pe PathElement) Idx(length int) (int, error) {
+	if !pe.IsField() {
+		return ResolveIdx(pe.ArrayIdx, length)
+	}
+
+	idx, err := ParseArrayIdx(pe.ObjectField)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return ResolveIdx(idx, length)
+}
+
+var arrayIdxRE = regexp.MustCompile(`^(0|[1-9][0-9]*)$`)
+
+// ParseArrayIdx accepts non-negative decimal numbers without leading zeros
+func ParseArrayIdx(s string) (int, error) {
+	if !arrayIdxRE.MatchString(s) {
+		return 0, ErrInvalidIdx
+	}
+
+	idx, err := strconv.Atoi(s)
+
+	if err != nil {
+		return 0, ErrInvalidIdx
+	}
+
+	return idx, nil
+}
+
+// Slice follows python semantics: bounds can be negative
+// to count from the end and are clamped to the array length,
+// missing bounds mean the whole array in the direction of the step
+type Slice struct {
+	Start *int
+	End   *int
+	Step  *int
+}
+
+// Indices returns indices of the elements of an array of the given
+// length that fall into the slice in ascending order, regardless of the step
+func (s *Slice) Indices(length int) []int {
+	step := 1
+
+	if s.Step != nil {
+		step = *s.Step
+	}
+
+	// zero step is rejected by the parser
+	if step == 0 {
+		return nil
+	}
+
+	bound := func(v *int, def int) int {
+		if v == nil {
+			return def
+		}
+
+		idx := *v
+
+		if idx < 0 {
+			idx += length
+		}
+
+		lower, upper := 0, length
+
+		if step < 0 {
+			lower, upper = -1, length-1
+		}
+
+		if idx < lower {
+			return lower
+		}
+
+		if idx > upper {
+			return upper
+		}
+
+		return idx
+	}
+
+	out := []int{}
+
+	if step > 0 {
+		for idx := bound(s.Start, 0); idx < bound(s.End, length); idx += step {
+			out = append(out, idx)
+		}
+
+		return out
+	}
+
+	for idx := bound(s.Start, length-1); idx > bound(s.End, -1); idx += step {
+		out = append(out, idx)
+	}
+
+	sort.Ints(out)
+
+	return out
+}
+
+func (s *Slice) String() string {
+	var buf bytes.Buffer
+
+	if s.Start != nil {
+		buf.WriteString(strconv.Itoa(*s.Start))
+	}
+
+	buf.WriteRune(':')
+
+	if s.End != nil {
+		buf.WriteString(strconv.Itoa(*s.End))
+	}
+
+	if s.Step != nil {
+		buf.WriteRune(':')
+		buf.WriteString(strconv.Itoa(*s.Step))
+	}
+
+	return buf.String()
+}
+
+// Predicate is a condition checked against a node
+type Predicate interface {
+	Match(node Node) bool
+	String() string
 }
 
 func (pe PathElement) String() string {
-	if pe.ObjectField != "" {
+	if pe.Wildcard {
+		return "*"
+	}
+
+	if pe.Recursive {
+		return ".."
+	}
+
+	if pe.Filter != nil {
+		return "?(" + pe.Filter.String() + ")"
+	}
+
+	if pe.Slice != nil {
+		return pe.Slice.String()
+	}
+
+	if pe.IsField() {
 		return pe.ObjectField
 	}
 
 	return strconv.Itoa(pe.ArrayIdx)
 }
 
+// ResolveIdx turns a negative index into the one counting from
+// the end of the array, -1 is the last element
+func ResolveIdx(idx int, length int) (int, error) {
+	if idx < 0 {
+		idx += length
+	}
+
+	if idx < 0 || idx >= length {
+		return 0, ErrIdxOutOfBounds
+	}
+
+	return idx, nil
+}
+
+// ResolveInsertIdx checks that the value can be inserted at the index,
+// the index right after the last element is fine too
+func ResolveInsertIdx(idx int, length int) (int, error) {
+	if idx < 0 || idx > length {
+		return 0, ErrIdxOutOfBounds
+	}
+
+	return idx, nil
+}
+
 type PathElementSlice []PathElement
 
 func (sl PathElementSlice) String() string {
@@ -42,10 +239,39 @@ func (sl PathElementSlice) String() string {
 	for idx := 0; idx < len(sl); idx++ {
 		p := sl[idx]
 
-		if p.ObjectField != "" {
-			if idx != 0 {
+		if p.Wildcard {
+			buf.WriteString("[*]")
+			continue
+		}
+
+		if p.Recursive {
+			buf.WriteString("..")
+			continue
+		}
+
+		if p.Filter != nil {
+			buf.WriteString("[?(")
+			buf.WriteString(p.Filter.String())
+			buf.WriteString(")]")
+			continue
+		}
+
+		if p.Slice != nil {
+			buf.WriteRune('[')
+			buf.WriteString(p.Slice.String())
+			buf.WriteRune(']')
+			continue
+		}
+
+		if p.IsField() {
+			if idx != 0 && !sl[idx-1].Recursive {
 				buf.WriteRune('.')
 			}
+
+			if p.EmptyKey {
+				buf.WriteString(`""`)
+			}
+
 			buf.WriteString(p.ObjectField)
 			continue
 		}
@@ -65,6 +291,16 @@ type Node interface {
 	GetField(field PathElement) (any, error)
 	SetField(field PathElement, value any) error
 	DeleteField(field PathElement) error
+	// InsertField inserts the value into an array before the element
+	// at the index, index equal to the length of the array appends the value
+	InsertField(field PathElement, value any) error
+	// Fields returns keys of an object or indices of an array
+	// in the document order, scalars have no fields
+	Fields() []PathElement
+	// Snapshot saves the state of the node and everything below it,
+	// calling restore brings the node back to that state. Restore
+	// is supposed to be called at most once
+	Snapshot() (restore func())
 }
 
 type RootNode interface {
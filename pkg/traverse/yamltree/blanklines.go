@@ -0,0 +1,326 @@
+package yamltree
+
+import (
+	"bytes"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yaml.v3 does not keep track of blank lines, hence every
+// serialization removes them. Line alignment is too expensive
+// for huge documents that were changed all over the place, we just
+// give up in this case
+const maxAlignmentCells = 1 << 22
+
+// restoreLayout aligns lines of the original and serialized
+// documents and puts blank lines and document end markers from the
+// original document back, right before the line that used to follow
+// them. Blank lines that used to surround deleted lines are dropped.
+// Lines with comments are taken from the original document as is to
+// keep the spacing before the comment. inScalar marks lines of the
+// original document that belong to block scalars, blank lines there
+// are a part of the value.
+func restoreLayout(orig []byte, out []byte, lines []outputLine, inScalar []bool) []byte {
+	trailingNewline := bytes.HasSuffix(out, []byte("\n"))
+
+	origLines := bytes.Split(bytes.TrimSuffix(orig, []byte("\n")), []byte("\n"))
+	outLines := bytes.Split(bytes.TrimSuffix(out, []byte("\n")), []byte("\n"))
+
+	matches, ok := alignLines(normalizeLines(origLines), normalizeLines(outLines))
+
+	if !ok {
+		return out
+	}
+
+	origIdx := make([]int, len(outLines))
+
+	for idx := range origIdx {
+		origIdx[idx] = -1
+	}
+
+	for idx, match := range matches {
+		if match >= 0 {
+			origIdx[match] = idx
+		}
+	}
+
+	// every run of blank lines that is missing in the output
+	// is attached to the next line that is present in both documents
+	insertBefore := make([][][]byte, len(outLines))
+	var trailing [][]byte
+	run := 0
+
+	for idx, line := range origLines {
+		if matches[idx] >= 0 || !isFiller(line) || (idx < len(inScalar) && inScalar[idx]) {
+			run = 0
+			continue
+		}
+
+		run++
+
+		next := nextMatch(matches, idx)
+
+		// yaml.v3 never writes document end markers,
+		// the one at the end of the stream is always kept
+		if next < 0 && hasEndMarker(origLines[idx+1-run:idx+1]) {
+			trailing = origLines[idx+1-run : idx+1]
+		}
+
+		if next < 0 || !survived(matches, idx-run, next) {
+			continue
+		}
+
+		// if several runs end up in the same place, e.g. because
+		// the lines between them were deleted, they are not added up
+		at := matches[next]
+
+		if len(insertBefore[at]) < run {
+			insertBefore[at] = origLines[idx+1-run : idx+1]
+		}
+	}
+
+	var buf bytes.Buffer
+
+	for idx, line := range outLines {
+		for _, filler := range insertBefore[idx] {
+			buf.Write(filler)
+			buf.WriteByte('\n')
+		}
+
+		if origIdx[idx] >= 0 && lines[idx].comment {
+			line = origLines[origIdx[idx]]
+		}
+
+		buf.Write(line)
+
+		if idx < len(outLines)-1 || trailingNewline || len(trailing) > 0 {
+			buf.WriteByte('\n')
+		}
+	}
+
+	for idx, filler := range trailing {
+		buf.Write(filler)
+
+		if idx < len(trailing)-1 || trailingNewline {
+			buf.WriteByte('\n')
+		}
+	}
+
+	return buf.Bytes()
+}
+
+// isFiller reports whether the line is something yaml.v3 never
+// writes: a blank line or a document end marker
+func isFiller(line []byte) bool {
+	trimmed := bytes.TrimSpace(line)
+
+	return len(trimmed) == 0 || bytes.Equal(trimmed, []byte("..."))
+}
+
+func hasEndMarker(lines [][]byte) bool {
+	for _, line := range lines {
+		if bytes.Equal(bytes.TrimSpace(line), []byte("...")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// blockScalarLines marks lines of the source that belong to literal and
+// folded scalars, from the first line of the content to the last one
+func blockScalarLines(docs []*yaml.Node, source []byte) []bool {
+	lines := bytes.Split(source, []byte("\n"))
+	inScalar := make([]bool, len(lines))
+
+	var walk func(n *yaml.Node)
+
+	walk = func(n *yaml.Node) {
+		for _, c := range n.Content {
+			walk(c)
+		}
+
+		if n.Kind != yaml.ScalarNode || n.Style&(yaml.LiteralStyle|yaml.FoldedStyle) == 0 || n.Line < 1 || n.Line > len(lines) {
+			return
+		}
+
+		// the indicator line is the one the scalar starts at
+		parentIndent := indentation(lines[n.Line-1])
+		contentIndent := -1
+		last := -1
+
+		for idx := n.Line; idx < len(lines); idx++ {
+			if len(bytes.TrimSpace(lines[idx])) == 0 {
+				continue
+			}
+
+			ind := indentation(lines[idx])
+
+			if contentIndent < 0 {
+				if ind <= parentIndent {
+					break
+				}
+
+				contentIndent = ind
+			}
+
+			if ind < contentIndent {
+				break
+			}
+
+			last = idx
+		}
+
+		for idx := n.Line; idx <= last; idx++ {
+			inScalar[idx] = true
+		}
+	}
+
+	for _, doc := range docs {
+		walk(doc)
+	}
+
+	return inScalar
+}
+
+// normalizeLines drops the differences yaml.v3 does not keep:
+// trailing whitespace and the spacing before line comments
+func normalizeLines(lines [][]byte) [][]byte {
+	out := make([][]byte, 0, len(lines))
+
+	for _, line := range lines {
+		line = bytes.TrimRight(line, " \t")
+		normalized := make([]byte, 0, len(line))
+
+		for idx := 0; idx < len(line); idx++ {
+			if line[idx] == ' ' || line[idx] == '\t' {
+				end := idx
+
+				for end < len(line) && (line[end] == ' ' || line[end] == '\t') {
+					end++
+				}
+
+				if idx > 0 && end < len(line) && line[end] == '#' {
+					normalized = append(normalized, ' ')
+				} else {
+					normalized = append(normalized, line[idx:end]...)
+				}
+
+				idx = end - 1
+				continue
+			}
+
+			normalized = append(normalized, line[idx])
+		}
+
+		out = append(out, normalized)
+	}
+
+	return out
+}
+
+func nextMatch(matches []int, idx int) int {
+	for next := idx + 1; next < len(matches); next++ {
+		if matches[next] >= 0 {
+			return next
+		}
+	}
+
+	return -1
+}
+
+// survived reports whether the original line at idx is still
+// present in the output in one form or another. Unmatched line
+// is considered to be changed rather than deleted if there is any
+// new line in the output in the same region.
+func survived(matches []int, idx int, next int) bool {
+	// beginning of the document
+	if idx < 0 {
+		return true
+	}
+
+	if matches[idx] >= 0 {
+		return true
+	}
+
+	prevOut := -1
+
+	for prev := idx - 1; prev >= 0; prev-- {
+		if matches[prev] >= 0 {
+			prevOut = matches[prev]
+			break
+		}
+	}
+
+	return matches[next]-prevOut > 1
+}
+
+// alignLines returns an index of the matching line in b for every line in a
+// or -1 if the line has no match. Matching is done with the longest
+// common subsequence of lines, common prefix and suffix are matched
+// upfront since typically only a few lines change.
+func alignLines(a [][]byte, b [][]byte) ([]int, bool) {
+	matches := make([]int, len(a))
+
+	for idx := range matches {
+		matches[idx] = -1
+	}
+
+	prefix := 0
+
+	for prefix < len(a) && prefix < len(b) && bytes.Equal(a[prefix], b[prefix]) {
+		matches[prefix] = prefix
+		prefix++
+	}
+
+	suffix := 0
+
+	for suffix < len(a)-prefix && suffix < len(b)-prefix &&
+		bytes.Equal(a[len(a)-1-suffix], b[len(b)-1-suffix]) {
+		matches[len(a)-1-suffix] = len(b) - 1 - suffix
+		suffix++
+	}
+
+	midA := a[prefix : len(a)-suffix]
+	midB := b[prefix : len(b)-suffix]
+
+	if len(midA) == 0 || len(midB) == 0 {
+		return matches, true
+	}
+
+	if len(midA)*len(midB) > maxAlignmentCells {
+		return nil, false
+	}
+
+	width := len(midB) + 1
+	lcs := make([]int32, (len(midA)+1)*width)
+
+	for i := len(midA) - 1; i >= 0; i-- {
+		for j := len(midB) - 1; j >= 0; j-- {
+			if bytes.Equal(midA[i], midB[j]) {
+				lcs[i*width+j] = lcs[(i+1)*width+j+1] + 1
+			} else if lcs[(i+1)*width+j] >= lcs[i*width+j+1] {
+				lcs[i*width+j] = lcs[(i+1)*width+j]
+			} else {
+				lcs[i*width+j] = lcs[i*width+j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+
+	for i < len(midA) && j < len(midB) {
+		switch {
+		case bytes.Equal(midA[i], midB[j]):
+			matches[prefix+i] = prefix + j
+			i++
+			j++
+		case lcs[(i+1)*width+j] >= lcs[i*width+j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+
+	return matches, true
+}
@@ -0,0 +1,171 @@
+package yamltree
+
+import (
+	"bytes"
+	"io"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/can3p/sackmesser/pkg/traverse/types"
+)
+
+// ParseAll parses every document of a multi-document stream,
+// at least one document is always returned, empty input is
+// treated as a null document
+func ParseAll(b []byte) ([]types.RootNode, error) {
+	input := b
+
+	// yaml.v3 attaches comments differently if lines end with \r\n,
+	// line endings are restored during the serialization
+	if hasCRLF(b) {
+		input = bytes.ReplaceAll(b, []byte("\r\n"), []byte("\n"))
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(input))
+	docs := []*yaml.Node{}
+
+	for {
+		var doc yaml.Node
+
+		err := dec.Decode(&doc)
+
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		docs = append(docs, &doc)
+	}
+
+	// empty input, let's pretend it was a null
+	if len(docs) == 0 {
+		docs = append(docs, &yaml.Node{
+			Kind:    yaml.DocumentNode,
+			Content: []*yaml.Node{{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}},
+		})
+	}
+
+	indent := 0
+
+	for _, doc := range docs {
+		if indent = detectIndent(doc); indent > 0 {
+			break
+		}
+	}
+
+	if indent == 0 {
+		indent = defaultIndent
+	}
+
+	// documents without block sequences of their own
+	// get the style of the first document that has them
+	streamCompact := false
+
+	for _, doc := range docs {
+		if compact, ok := detectCompactSequences(doc); ok {
+			streamCompact = compact
+			break
+		}
+	}
+
+	out := make([]types.RootNode, 0, len(docs))
+
+	for _, doc := range docs {
+		clearMergeTags(doc)
+
+		compact, ok := detectCompactSequences(doc)
+
+		if !ok {
+			compact = streamCompact
+		}
+
+		out = append(out, &yroot{
+			ynode:       &ynode{n: doc},
+			indent:      indent,
+			compactSeqs: compact,
+			source:      b,
+		})
+	}
+
+	return out, nil
+}
+
+// SerializeAll serializes nodes as a multi-document stream in the
+// given order. Formatting is kept for nodes of a yaml tree,
+// any other nodes are converted
+func SerializeAll(nodes []types.Node) ([]byte, error) {
+	docs := make([]*yaml.Node, 0, len(nodes))
+	indent := defaultIndent
+	compactSeqs := make([]bool, 0, len(nodes))
+	var source []byte
+
+	for idx, n := range nodes {
+		if root, ok := FromNode(n); ok {
+			typed := root.(*yroot)
+
+			docs = append(docs, typed.n)
+			compactSeqs = append(compactSeqs, typed.compactSeqs)
+
+			if idx == 0 {
+				indent = typed.indent
+				source = typed.source
+			}
+
+			continue
+		}
+
+		doc, err := Encode(n.Value())
+
+		if err != nil {
+			return nil, err
+		}
+
+		docs = append(docs, doc)
+		compactSeqs = append(compactSeqs, false)
+	}
+
+	return serialize(docs, indent, compactSeqs, source)
+}
+
+// serialize writes the documents, compactSeqs tells
+// the sequence style of every document
+func serialize(docs []*yaml.Node, indent int, compactSeqs []bool, source []byte) ([]byte, error) {
+	crlf := hasCRLF(source)
+
+	if crlf {
+		source = bytes.ReplaceAll(source, []byte("\r\n"), []byte("\n"))
+	}
+
+	out, err := render(docs, indent, compactSeqs, hasExplicitStart(source))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if source == nil {
+		return out.text, nil
+	}
+
+	result := keepLayout(source, out, indent, compactSeqs)
+
+	if crlf {
+		result = bytes.ReplaceAll(result, []byte("\n"), []byte("\r\n"))
+	}
+
+	return result, nil
+}
+
+func hasExplicitStart(source []byte) bool {
+	firstLine, _, _ := bytes.Cut(source, []byte("\n"))
+
+	return bytes.Equal(bytes.TrimSpace(firstLine), []byte("---"))
+}
+
+// hasCRLF reports whether lines of the source end with \r\n,
+// the first line decides for the whole document
+func hasCRLF(source []byte) bool {
+	firstLine, _, found := bytes.Cut(source, []byte("\n"))
+
+	return found && bytes.HasSuffix(firstLine, []byte("\r"))
+}
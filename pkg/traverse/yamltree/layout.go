@@ -0,0 +1,147 @@
+package yamltree
+
+import (
+	"bytes"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// outputLine is what is known about a line of the serialized stream
+type outputLine struct {
+	// shift is the number of spaces the line has to be
+	// moved to the left to get compact sequences
+	shift int
+	// comment is set for the lines that end with a line comment
+	comment bool
+}
+
+// inspectOutput parses the serialized stream back, since yaml.v3
+// does not tell where the nodes end up. Lines of the documents
+// with compact sequences get the shift to compact them
+func inspectOutput(out []byte, compactSeqs []bool) ([]outputLine, []*yaml.Node, bool) {
+	text := bytes.Split(out, []byte("\n"))
+	lines := make([]outputLine, len(text))
+	docs := []*yaml.Node{}
+	dec := yaml.NewDecoder(bytes.NewReader(out))
+
+	for {
+		var doc yaml.Node
+
+		err := dec.Decode(&doc)
+
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, nil, false
+		}
+
+		compact := len(docs) < len(compactSeqs) && compactSeqs[len(docs)]
+
+		inspectNode(&doc, text, lines, compact)
+		docs = append(docs, &doc)
+	}
+
+	return lines, docs, true
+}
+
+func inspectNode(n *yaml.Node, text [][]byte, lines []outputLine, compact bool) {
+	if n.LineComment != "" {
+		lines[n.Line-1].comment = true
+	}
+
+	if n.Kind == yaml.MappingNode && n.Style&yaml.FlowStyle == 0 {
+		for idx := 1; idx < len(n.Content); idx += 2 {
+			key, value := n.Content[idx-1], n.Content[idx]
+
+			// comment of a pair might be attached to a value that
+			// starts on the next line
+			if value.LineComment != "" {
+				lines[key.Line-1].comment = true
+			}
+
+			if compact && value.Kind == yaml.SequenceNode && value.Style&yaml.FlowStyle == 0 && value.Line > key.Line {
+				markShift(text, lines, key, value.Column-key.Column)
+			}
+		}
+	}
+
+	for _, c := range n.Content {
+		inspectNode(c, text, lines, compact)
+	}
+}
+
+// markShift moves all the lines that follow the key and are indented
+// deeper than the key, they belong to the value by definition
+func markShift(text [][]byte, lines []outputLine, key *yaml.Node, shift int) {
+	for idx := key.Line; idx < len(text); idx++ {
+		if line := text[idx]; len(bytes.TrimSpace(line)) > 0 && indentation(line) < key.Column {
+			break
+		}
+
+		lines[idx].shift += shift
+	}
+}
+
+func indentation(line []byte) int {
+	return len(line) - len(bytes.TrimLeft(line, " "))
+}
+
+// compactSequences writes block sequences of mappings at the same
+// column as the key, yaml.v3 always indents them. The shift of every
+// line is updated to the number of spaces actually removed
+func compactSequences(out []byte, lines []outputLine) []byte {
+	var buf bytes.Buffer
+
+	for idx, line := range bytes.Split(out, []byte("\n")) {
+		if idx > 0 {
+			buf.WriteByte('\n')
+		}
+
+		lines[idx].shift = min(lines[idx].shift, indentation(line))
+		buf.Write(line[lines[idx].shift:])
+	}
+
+	return buf.Bytes()
+}
+
+// detectCompactSequences looks for the first block sequence nested in
+// a block mapping and reports whether its items start at the column of
+// the key, e.g. the way kubectl writes them. The second value is false
+// if no such sequence was found
+func detectCompactSequences(n *yaml.Node) (bool, bool) {
+	if n.Kind == yaml.MappingNode && n.Style&yaml.FlowStyle == 0 {
+		for idx := 1; idx < len(n.Content); idx += 2 {
+			key, value := n.Content[idx-1], n.Content[idx]
+
+			if value.Kind == yaml.SequenceNode && value.Style&yaml.FlowStyle == 0 && value.Line > key.Line {
+				return value.Column == key.Column, true
+			}
+		}
+	}
+
+	for _, c := range n.Content {
+		if compact, ok := detectCompactSequences(c); ok {
+			return compact, true
+		}
+	}
+
+	return false, false
+}
+
+// clearMergeTags drops explicit tags of merge keys, yaml.v3 sets them
+// while parsing and prints them back as !!merge <<. Merge keys without
+// a tag are still resolved as such
+func clearMergeTags(n *yaml.Node) {
+	if n.Kind == yaml.MappingNode {
+		for idx := 0; idx < len(n.Content); idx += 2 {
+			if key := n.Content[idx]; key.Kind == yaml.ScalarNode && key.Tag == "!!merge" {
+				key.Tag = ""
+			}
+		}
+	}
+
+	for _, c := range n.Content {
+		clearMergeTags(c)
+	}
+}
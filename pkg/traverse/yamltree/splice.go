@@ -0,0 +1,390 @@
+package yamltree
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+// rendering is the output of yaml.v3 along with
+// the documents parsed back from it
+type rendering struct {
+	text  []byte
+	lines []outputLine
+	docs  []*yaml.Node
+}
+
+func render(docs []*yaml.Node, indent int, compactSeqs []bool, explicitStart bool) (*rendering, error) {
+	var buf bytes.Buffer
+
+	// yaml encoder never starts a stream with a document marker
+	if explicitStart {
+		buf.WriteString("---\n")
+	}
+
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(indent)
+
+	for _, doc := range docs {
+		if err := enc.Encode(doc); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+
+	r := &rendering{text: dropEmptyDocumentLines(buf.Bytes(), docs)}
+
+	lines, parsed, ok := inspectOutput(r.text, compactSeqs)
+
+	if !ok {
+		return r, nil
+	}
+
+	r.lines = lines
+	r.docs = parsed
+	r.text = compactSequences(r.text, lines)
+
+	return r, nil
+}
+
+// dropEmptyDocumentLines removes the blank lines yaml.v3
+// writes for the documents without any content, e.g. for
+// the one between two --- lines
+func dropEmptyDocumentLines(text []byte, docs []*yaml.Node) []byte {
+	empty := false
+
+	for _, doc := range docs {
+		empty = empty || isEmptyDocument(doc)
+	}
+
+	if !empty {
+		return text
+	}
+
+	marker := []byte("---")
+	lines := bytes.Split(text, []byte("\n"))
+	out := make([][]byte, 0, len(lines))
+
+	for idx, line := range lines {
+		last := idx == len(lines)-2 && len(lines[idx+1]) == 0
+
+		if len(line) == 0 && idx > 0 && bytes.Equal(lines[idx-1], marker) &&
+			(last || idx+1 < len(lines) && bytes.Equal(lines[idx+1], marker)) {
+			continue
+		}
+
+		out = append(out, line)
+	}
+
+	return bytes.Join(out, []byte("\n"))
+}
+
+func isEmptyDocument(doc *yaml.Node) bool {
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) != 1 {
+		return false
+	}
+
+	n := doc.Content[0]
+
+	return n.Kind == yaml.ScalarNode && n.ShortTag() == "!!null" && n.Value == "" &&
+		n.HeadComment == "" && n.LineComment == "" && n.FootComment == ""
+}
+
+// column returns the zero based column of the node in the text,
+// positions of the parsed documents do not know about compaction
+func (r *rendering) column(n *yaml.Node) int {
+	return n.Column - 1 - r.lines[n.Line-1].shift
+}
+
+// anchor is a node that is present both in the original
+// document and in its rendering, positions are zero based
+type anchor struct {
+	outLine int
+	outCol  int
+	srcLine int
+	srcCol  int
+	// only nodes of block collections define indentation
+	block bool
+}
+
+// keepLayout brings the original text back for everything that has not been
+// changed. yaml.v3 does not keep the layout of the original document: folded
+// scalars, indentation, document end markers and blank lines are lost. The
+// original document is rendered the same way as the output is, the difference
+// between the two renderings is what has been actually changed, the rest is
+// taken from the source. If the result is not equivalent to the output for any
+// reason, the output is returned with just blank lines restored
+func keepLayout(source []byte, out *rendering, indent int, compactSeqs []bool) []byte {
+	if out.lines == nil {
+		return out.text
+	}
+
+	expected, ok := decodeAll(out.text)
+
+	if !ok {
+		return out.text
+	}
+
+	srcDocs, ok := parseAll(source)
+
+	if !ok {
+		return restoreLayout(source, out.text, out.lines, nil)
+	}
+
+	inScalar := blockScalarLines(srcDocs, source)
+	candidates := [][]byte{}
+
+	if spliced, lines, ok := splice(source, srcDocs, out, indent, compactSeqs); ok {
+		candidates = append(candidates, restoreLayout(source, spliced, lines, inScalar), spliced)
+	}
+
+	candidates = append(candidates, restoreLayout(source, out.text, out.lines, inScalar))
+
+	for _, candidate := range candidates {
+		if values, ok := decodeAll(candidate); ok && reflect.DeepEqual(expected, values) {
+			return candidate
+		}
+	}
+
+	return out.text
+}
+
+func splice(source []byte, srcDocs []*yaml.Node, out *rendering, indent int, compactSeqs []bool) ([]byte, []outputLine, bool) {
+	for _, doc := range srcDocs {
+		clearMergeTags(doc)
+	}
+
+	orig, err := render(srcDocs, indent, compactSeqs, hasExplicitStart(source))
+
+	if err != nil || orig.lines == nil || len(orig.docs) != len(srcDocs) {
+		return nil, nil, false
+	}
+
+	anchors := []anchor{}
+
+	for idx := range srcDocs {
+		if !collectAnchors(orig, orig.docs[idx], srcDocs[idx], false, false, &anchors) {
+			return nil, nil, false
+		}
+	}
+
+	srcLines := bytes.Split(source, []byte("\n"))
+	origLines := bytes.Split(orig.text, []byte("\n"))
+	outLines := bytes.Split(out.text, []byte("\n"))
+
+	matches, ok := alignLines(origLines, outLines)
+
+	if !ok {
+		return nil, nil, false
+	}
+
+	// chunks span from one node to the next one, a chunk of the rendering
+	// of the original document that has not been changed is replaced
+	// with the corresponding chunk of the source
+	type chunk struct {
+		origStart, origEnd int
+		srcStart, srcEnd   int
+	}
+
+	chunks := []chunk{}
+	prev := chunk{}
+
+	for _, a := range anchors {
+		if a.outLine <= prev.origStart || a.srcLine <= prev.srcStart {
+			continue
+		}
+
+		prev.origEnd, prev.srcEnd = a.outLine, a.srcLine
+		chunks = append(chunks, prev)
+		prev = chunk{origStart: a.outLine, srcStart: a.srcLine}
+	}
+
+	prev.origEnd, prev.srcEnd = len(origLines), len(srcLines)
+	chunks = append(chunks, prev)
+
+	// index of the chunk that starts at the output line
+	chunkAt := make([]int, len(outLines))
+
+	for idx := range chunkAt {
+		chunkAt[idx] = -1
+	}
+
+	for idx, c := range chunks {
+		start := matches[c.origStart]
+		intact := start >= 0
+
+		for line := c.origStart; intact && line < c.origEnd; line++ {
+			intact = matches[line] == start+line-c.origStart
+		}
+
+		if intact {
+			chunkAt[start] = idx
+		}
+	}
+
+	origIdx := make([]int, len(outLines))
+
+	for idx := range origIdx {
+		origIdx[idx] = -1
+	}
+
+	for idx, match := range matches {
+		if match >= 0 {
+			origIdx[match] = idx
+		}
+	}
+
+	var result [][]byte
+	var lines []outputLine
+
+	// the most recent nodes of the original document, every one
+	// is indented deeper than the previous one
+	stack := []anchor{}
+	next := 0
+
+	passAnchors := func(origLine int) {
+		for ; next < len(anchors) && anchors[next].outLine <= origLine; next++ {
+			a := anchors[next]
+
+			if !a.block {
+				continue
+			}
+
+			for len(stack) > 0 && stack[len(stack)-1].outCol >= a.outCol {
+				stack = stack[:len(stack)-1]
+			}
+
+			stack = append(stack, a)
+		}
+	}
+
+	for idx := 0; idx < len(outLines); {
+		if c := chunkAt[idx]; c >= 0 {
+			result = append(result, srcLines[chunks[c].srcStart:chunks[c].srcEnd]...)
+
+			for line := chunks[c].srcStart; line < chunks[c].srcEnd; line++ {
+				lines = append(lines, outputLine{})
+			}
+
+			passAnchors(chunks[c].origEnd - 1)
+			idx += chunks[c].origEnd - chunks[c].origStart
+			continue
+		}
+
+		if origIdx[idx] >= 0 {
+			passAnchors(origIdx[idx])
+		}
+
+		result = append(result, reindent(outLines[idx], stack))
+		lines = append(lines, out.lines[idx])
+		idx++
+	}
+
+	return bytes.Join(result, []byte("\n")), lines, true
+}
+
+// reindent moves a changed line the same way the closest node of the
+// original document it's nested into was moved. Lines nested deeper than
+// the node use the indentation the node has relative to its parent
+func reindent(line []byte, stack []anchor) []byte {
+	if len(bytes.TrimSpace(line)) == 0 {
+		return line
+	}
+
+	ind := indentation(line)
+
+	for idx := len(stack) - 1; idx >= 0; idx-- {
+		a := stack[idx]
+
+		if a.outCol > ind {
+			continue
+		}
+
+		shifted := ind + a.srcCol - a.outCol
+
+		if idx > 0 && ind > a.outCol {
+			parent := stack[idx-1]
+			outStep, srcStep := a.outCol-parent.outCol, a.srcCol-parent.srcCol
+
+			if outStep > 0 && srcStep > 0 && (ind-a.outCol)%outStep == 0 {
+				shifted = a.srcCol + (ind-a.outCol)/outStep*srcStep
+			}
+		}
+
+		return append(bytes.Repeat([]byte(" "), max(shifted, 0)), line[ind:]...)
+	}
+
+	return line
+}
+
+// collectAnchors walks both trees in parallel, the trees are
+// expected to be the same since one is the rendering of another
+func collectAnchors(r *rendering, out *yaml.Node, src *yaml.Node, flow bool, key bool, anchors *[]anchor) bool {
+	if out.Kind != src.Kind || len(out.Content) != len(src.Content) {
+		return false
+	}
+
+	if out.Kind != yaml.DocumentNode {
+		*anchors = append(*anchors, anchor{
+			outLine: out.Line - 1,
+			outCol:  r.column(out),
+			srcLine: src.Line - 1,
+			srcCol:  src.Column - 1,
+			// keys define indentation of the values that follow them
+			block: !flow && (key || out.Kind == yaml.MappingNode || out.Kind == yaml.SequenceNode),
+		})
+	}
+
+	flow = flow || out.Style&yaml.FlowStyle != 0
+
+	for idx := range out.Content {
+		if !collectAnchors(r, out.Content[idx], src.Content[idx], flow, out.Kind == yaml.MappingNode && idx%2 == 0, anchors) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func parseAll(b []byte) ([]*yaml.Node, bool) {
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	docs := []*yaml.Node{}
+
+	for {
+		var doc yaml.Node
+
+		err := dec.Decode(&doc)
+
+		if err == io.EOF {
+			return docs, true
+		} else if err != nil {
+			return nil, false
+		}
+
+		docs = append(docs, &doc)
+	}
+}
+
+func decodeAll(b []byte) ([]any, bool) {
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	values := []any{}
+
+	for {
+		var v any
+
+		err := dec.Decode(&v)
+
+		if err == io.EOF {
+			return values, true
+		} else if err != nil {
+			return nil, false
+		}
+
+		values = append(values, v)
+	}
+}
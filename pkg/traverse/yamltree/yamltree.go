@@ -0,0 +1,653 @@
+// Package yamltree implements types.Node on top of yaml.v3 node trees.
+//
+// Unlike simpleyaml, which unmarshals a document into plain go values,
+// the tree is edited in place, hence comments, key order, anchors
+// and quoting styles survive the modification. The text of the nodes
+// that have not been changed is copied from the source as is, changed
+// nodes are written the way yaml.v3 writes them, using the indentation
+// of the surrounding lines.
+package yamltree
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/can3p/sackmesser/pkg/traverse/types"
+)
+
+const defaultIndent = 2
+
+type ynode struct {
+	n *yaml.Node
+	// parent, the field and the data node of the parent the node
+	// has been visited through, see writable
+	parent *ynode
+	field  types.PathElement
+	via    *yaml.Node
+}
+
+// target returns the node that holds the actual data, aliases
+// are followed. Use writable to get the node to modify
+func (n *ynode) target() *yaml.Node {
+	return resolve(n.n)
+}
+
+// writable returns the node that holds the actual data and can be
+// modified. An alias on the way from the root is replaced with a copy
+// of the anchored node first, since the modification should not be
+// visible through the anchor and the other aliases
+func (n *ynode) writable() (*yaml.Node, error) {
+	if n.parent != nil {
+		pt, err := n.parent.writable()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if pt != n.via {
+			child, err := childOf(pt, n.field)
+
+			if err != nil {
+				return nil, err
+			}
+
+			n.n, n.via = child, pt
+		}
+	}
+
+	if n.n.Kind == yaml.AliasNode && n.n.Alias != nil {
+		*n.n = *copyTree(resolve(n.n))
+	}
+
+	return n.target(), nil
+}
+
+// copyTree copies the node without the anchors, aliases are kept
+// as they are since they point to the nodes outside of the copy
+func copyTree(n *yaml.Node) *yaml.Node {
+	out := *n
+	out.Anchor = ""
+
+	if n.Kind == yaml.AliasNode {
+		return &out
+	}
+
+	out.Content = make([]*yaml.Node, 0, len(n.Content))
+
+	for _, c := range n.Content {
+		out.Content = append(out.Content, copyTree(c))
+	}
+
+	return &out
+}
+
+func resolve(n *yaml.Node) *yaml.Node {
+	for n.Kind == yaml.AliasNode && n.Alias != nil {
+		n = n.Alias
+	}
+
+	if n.Kind == yaml.DocumentNode && len(n.Content) > 0 {
+		return resolve(n.Content[0])
+	}
+
+	return n
+}
+
+func (n *ynode) Visit(field types.PathElement) (types.Node, error) {
+	t := n.target()
+	child, err := childOf(t, field)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &ynode{n: child, parent: n, field: field, via: t}, nil
+}
+
+func childOf(t *yaml.Node, field types.PathElement) (*yaml.Node, error) {
+	switch t.Kind {
+	case yaml.MappingNode:
+		if !field.IsField() {
+			return nil, types.ErrWrongVisit
+		}
+
+		idx := keyIdx(t, field.ObjectField)
+
+		if idx < 0 {
+			return nil, types.ErrFieldMissing
+		}
+
+		return t.Content[idx+1], nil
+	case yaml.SequenceNode:
+		idx, err := field.Idx(len(t.Content))
+
+		if err != nil {
+			return nil, err
+		}
+
+		return t.Content[idx], nil
+	}
+
+	return nil, types.ErrWrongVisit
+}
+
+func (n *ynode) GetField(field types.PathElement) (any, error) {
+	child, err := childOf(n.target(), field)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return decode(child)
+}
+
+func (n *ynode) SetField(field types.PathElement, value any) error {
+	t, err := n.writable()
+
+	if err != nil {
+		return err
+	}
+
+	switch t.Kind {
+	case yaml.MappingNode:
+		if !field.IsField() {
+			return types.ErrWrongVisit
+		}
+
+		idx := keyIdx(t, field.ObjectField)
+
+		if idx >= 0 {
+			return reconcile(t.Content[idx+1], value)
+		}
+
+		valueNode, err := Encode(value)
+
+		if err != nil {
+			return err
+		}
+
+		t.Content = append(t.Content, keyNode(field.ObjectField), valueNode)
+
+		return nil
+	case yaml.SequenceNode:
+		idx, err := field.Idx(len(t.Content))
+
+		if err != nil {
+			return err
+		}
+
+		return reconcile(t.Content[idx], value)
+	}
+
+	return types.ErrWrongVisit
+}
+
+func (n *ynode) DeleteField(field types.PathElement) error {
+	t, err := n.writable()
+
+	if err != nil {
+		return err
+	}
+
+	switch t.Kind {
+	case yaml.MappingNode:
+		if !field.IsField() {
+			return types.ErrWrongVisit
+		}
+
+		idx := keyIdx(t, field.ObjectField)
+
+		// nothing to do
+		if idx < 0 {
+			return nil
+		}
+
+		t.Content = append(t.Content[:idx], t.Content[idx+2:]...)
+
+		return nil
+	case yaml.SequenceNode:
+		idx, err := field.Idx(len(t.Content))
+
+		if err != nil {
+			return err
+		}
+
+		t.Content = append(t.Content[:idx], t.Content[idx+1:]...)
+
+		return nil
+	}
+
+	return types.ErrWrongVisit
+}
+
+// InsertField adds a new node, hence comments of the
+// existing elements stay with them
+func (n *ynode) InsertField(field types.PathElement, value any) error {
+	t, err := n.writable()
+
+	if err != nil {
+		return err
+	}
+
+	if t.Kind != yaml.SequenceNode {
+		return types.ErrWrongVisit
+	}
+
+	idx, err := types.ResolveInsertIdx(field.ArrayIdx, len(t.Content))
+
+	if err != nil {
+		return err
+	}
+
+	valueNode, err := Encode(value)
+
+	if err != nil {
+		return err
+	}
+
+	t.Content = append(t.Content[:idx], append([]*yaml.Node{valueNode}, t.Content[idx:]...)...)
+
+	return nil
+}
+
+func (n *ynode) Fields() []types.PathElement {
+	t := n.target()
+	out := []types.PathElement{}
+
+	switch t.Kind {
+	case yaml.MappingNode:
+		for idx := 0; idx+1 < len(t.Content); idx += 2 {
+			out = append(out, types.Field(t.Content[idx].Value))
+		}
+	case yaml.SequenceNode:
+		for idx := range t.Content {
+			out = append(out, types.PathElement{ArrayIdx: idx})
+		}
+	}
+
+	return out
+}
+
+// Snapshot saves every node of the tree by value, they are restored
+// in place, hence references to the nodes and aliases stay valid
+func (n *ynode) Snapshot() func() {
+	saved := map[*yaml.Node]yaml.Node{}
+	saveTree(n.n, saved)
+
+	return func() {
+		for ptr, node := range saved {
+			*ptr = node
+		}
+	}
+}
+
+// anchored nodes are saved as well, since they can be
+// modified through aliases even if they are outside of the tree
+func saveTree(n *yaml.Node, saved map[*yaml.Node]yaml.Node) {
+	if _, ok := saved[n]; ok {
+		return
+	}
+
+	node := *n
+
+	if n.Content != nil {
+		node.Content = append(make([]*yaml.Node, 0, len(n.Content)), n.Content...)
+	}
+
+	saved[n] = node
+
+	for _, c := range n.Content {
+		saveTree(c, saved)
+	}
+
+	if n.Alias != nil {
+		saveTree(n.Alias, saved)
+	}
+}
+
+func (n *ynode) Value() any {
+	// decoding of a valid tree should never fail,
+	// and there is no way to surface the error anyway
+	v, _ := decode(n.n)
+
+	return v
+}
+
+func (n *ynode) NodeType() types.NodeType {
+	t := n.target()
+
+	switch t.Kind {
+	case yaml.MappingNode:
+		return types.NodeTypeObject
+	case yaml.SequenceNode:
+		return types.NodeTypeArray
+	case yaml.ScalarNode:
+		switch t.ShortTag() {
+		case "!!null":
+			return types.NodeTypeNull
+		case "!!bool":
+			return types.NodeTypeBool
+		case "!!int", "!!float":
+			return types.NodeTypeNumber
+		}
+
+		return types.NodeTypeString
+	}
+
+	// empty document
+	return types.NodeTypeNull
+}
+
+type yroot struct {
+	*ynode
+	indent      int
+	compactSeqs bool
+	source      []byte
+}
+
+func (n *yroot) Serialize() ([]byte, error) {
+	return serialize([]*yaml.Node{n.n}, n.indent, []bool{n.compactSeqs}, n.source)
+}
+
+// FromNode makes any node of a yaml tree serializable,
+// false is returned if the node does not belong to a yaml tree
+func FromNode(n types.Node) (types.RootNode, bool) {
+	switch typed := n.(type) {
+	case *yroot:
+		return typed, true
+	case *ynode:
+		// the anchors might be defined outside of the subtree,
+		// hence we have to inline them
+		return &yroot{
+			ynode:  &ynode{n: inlineAliases(typed.target())},
+			indent: defaultIndent,
+		}, true
+	}
+
+	return nil, false
+}
+
+func inlineAliases(n *yaml.Node) *yaml.Node {
+	n = resolve(n)
+
+	out := *n
+	out.Anchor = ""
+	out.Content = make([]*yaml.Node, 0, len(n.Content))
+
+	for _, c := range n.Content {
+		out.Content = append(out.Content, inlineAliases(c))
+	}
+
+	return &out
+}
+
+// Parse returns the first document of the input,
+// see ParseAll to get all of them
+func Parse(b []byte) (types.RootNode, error) {
+	docs, err := ParseAll(b)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return docs[0], nil
+}
+
+func MustParse(b []byte) types.RootNode {
+	n, err := Parse(b)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return n
+}
+
+// detectIndent looks for the first nested block mapping
+// to figure out the indentation the document was written with,
+// zero is returned if no such mapping was found
+func detectIndent(n *yaml.Node) int {
+	if n.Kind == yaml.MappingNode && n.Style&yaml.FlowStyle == 0 {
+		for idx := 1; idx < len(n.Content); idx += 2 {
+			value := n.Content[idx]
+
+			if value.Kind == yaml.MappingNode && value.Style&yaml.FlowStyle == 0 && value.Line > n.Content[idx-1].Line {
+				if diff := value.Column - n.Content[idx-1].Column; diff > 0 {
+					return diff
+				}
+			}
+		}
+	}
+
+	for _, c := range n.Content {
+		if indent := detectIndent(c); indent > 0 {
+			return indent
+		}
+	}
+
+	return 0
+}
+
+func keyIdx(n *yaml.Node, key string) int {
+	for idx := 0; idx+1 < len(n.Content); idx += 2 {
+		if n.Content[idx].Value == key {
+			return idx
+		}
+	}
+
+	return -1
+}
+
+func keyNode(key string) *yaml.Node {
+	n := &yaml.Node{}
+	// encoding of a string cannot fail
+	_ = n.Encode(key)
+
+	return n
+}
+
+func decode(n *yaml.Node) (any, error) {
+	var v any
+
+	if err := n.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// Encode converts a plain go value into a yaml node. Unlike yaml.Node.Encode
+// it knows how to deal with json.Number, such values are represented as yaml
+// numbers with the original literal
+func Encode(value any) (*yaml.Node, error) {
+	n := &yaml.Node{}
+
+	if err := n.Encode(prepare(value)); err != nil {
+		return nil, err
+	}
+
+	return n, nil
+}
+
+func prepare(value any) any {
+	switch typed := value.(type) {
+	case json.Number:
+		// the tag is resolved by yaml, an explicit !!int would be printed
+		// for integers that do not fit into int64, they resolve to !!float
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: typed.String()}
+	case map[string]any:
+		out := make(map[string]any, len(typed))
+
+		for key, v := range typed {
+			out[key] = prepare(v)
+		}
+
+		return out
+	case []any:
+		out := make([]any, 0, len(typed))
+
+		for _, v := range typed {
+			out = append(out, prepare(v))
+		}
+
+		return out
+	}
+
+	return value
+}
+
+// reconcile updates the node to hold the value while touching as
+// little as possible: mappings and sequences are updated
+// recursively and scalars keep their style when the value has not changed.
+func reconcile(n *yaml.Node, value any) error {
+	t := resolve(n)
+
+	// an alias is replaced by the value itself,
+	// since we don't want to modify the anchored node
+	if n.Kind == yaml.AliasNode {
+		t = n
+	}
+
+	switch typed := value.(type) {
+	case map[string]any:
+		if t.Kind == yaml.MappingNode {
+			return reconcileMapping(t, typed)
+		}
+	case []any:
+		if t.Kind == yaml.SequenceNode {
+			return reconcileSequence(t, typed)
+		}
+	}
+
+	fresh, err := Encode(value)
+
+	if err != nil {
+		return err
+	}
+
+	if t.Kind == yaml.ScalarNode && fresh.Kind == yaml.ScalarNode && sameScalar(t, fresh) {
+		return nil
+	}
+
+	fresh.Anchor = t.Anchor
+	fresh.HeadComment = t.HeadComment
+	fresh.LineComment = t.LineComment
+	fresh.FootComment = t.FootComment
+
+	// quoting style of strings is preserved as long as the value
+	// is still a string
+	if t.Kind == yaml.ScalarNode && fresh.Kind == yaml.ScalarNode &&
+		t.ShortTag() == "!!str" && fresh.ShortTag() == "!!str" {
+		fresh.Style = t.Style
+	}
+
+	*t = *fresh
+
+	return nil
+}
+
+func reconcileMapping(n *yaml.Node, value map[string]any) error {
+	content := make([]*yaml.Node, 0, len(n.Content))
+	seen := map[string]struct{}{}
+
+	for idx := 0; idx+1 < len(n.Content); idx += 2 {
+		key := n.Content[idx].Value
+		v, ok := value[key]
+
+		if !ok {
+			continue
+		}
+
+		if err := reconcile(n.Content[idx+1], v); err != nil {
+			return err
+		}
+
+		seen[key] = struct{}{}
+		content = append(content, n.Content[idx], n.Content[idx+1])
+	}
+
+	newKeys := make([]string, 0, len(value))
+
+	for key := range value {
+		if _, ok := seen[key]; !ok {
+			newKeys = append(newKeys, key)
+		}
+	}
+
+	sort.Strings(newKeys)
+
+	for _, key := range newKeys {
+		valueNode, err := Encode(value[key])
+
+		if err != nil {
+			return err
+		}
+
+		content = append(content, keyNode(key), valueNode)
+	}
+
+	n.Content = content
+
+	return nil
+}
+
+func reconcileSequence(n *yaml.Node, value []any) error {
+	if len(value) < len(n.Content) {
+		n.Content = n.Content[:len(value)]
+	}
+
+	for idx, v := range value {
+		if idx < len(n.Content) {
+			if err := reconcile(n.Content[idx], v); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		valueNode, err := Encode(v)
+
+		if err != nil {
+			return err
+		}
+
+		n.Content = append(n.Content, valueNode)
+	}
+
+	return nil
+}
+
+// sameScalar reports whether both scalars hold the same value,
+// e.g. "1.10" and "1.1" are considered equal floats and "0x10"
+// is equal to "16"
+func sameScalar(a *yaml.Node, b *yaml.Node) bool {
+	if a.ShortTag() != b.ShortTag() {
+		return false
+	}
+
+	if a.Value == b.Value {
+		return true
+	}
+
+	av, err := decode(a)
+
+	if err != nil {
+		return false
+	}
+
+	bv, err := decode(b)
+
+	if err != nil {
+		return false
+	}
+
+	if at, ok := av.(time.Time); ok {
+		bt, ok := bv.(time.Time)
+		return ok && at.Equal(bt)
+	}
+
+	return av == bv
+}
@@ -0,0 +1,623 @@
+package yamltree
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/can3p/sackmesser/pkg/traverse/types"
+)
+
+func TestSetFieldPreservesFormatting(t *testing.T) {
+	initial := `# deployment
+kind: Deployment
+metadata:
+  name: app # the name
+  labels: &labels
+    app: 'web'
+
+spec:
+  replicas: 1
+  selector:
+    matchLabels: *labels
+  ports: [80, 443]
+`
+
+	examples := []struct {
+		description string
+		path        []types.PathElement
+		value       any
+		expected    string
+	}{
+		{
+			description: "update scalar",
+			path:        []types.PathElement{{ObjectField: "spec"}, {ObjectField: "replicas"}},
+			value:       3,
+			expected: `# deployment
+kind: Deployment
+metadata:
+  name: app # the name
+  labels: &labels
+    app: 'web'
+
+spec:
+  replicas: 3
+  selector:
+    matchLabels: *labels
+  ports: [80, 443]
+`,
+		},
+		{
+			description: "string keeps quoting style and line comment",
+			path:        []types.PathElement{{ObjectField: "metadata"}, {ObjectField: "name"}},
+			value:       "api",
+			expected: `# deployment
+kind: Deployment
+metadata:
+  name: api # the name
+  labels: &labels
+    app: 'web'
+
+spec:
+  replicas: 1
+  selector:
+    matchLabels: *labels
+  ports: [80, 443]
+`,
+		},
+		{
+			description: "update a mapping keeps the order",
+			path:        []types.PathElement{{ObjectField: "metadata"}, {ObjectField: "labels"}},
+			value:       map[string]any{"app": "api", "tier": "backend"},
+			expected: `# deployment
+kind: Deployment
+metadata:
+  name: app # the name
+  labels: &labels
+    app: 'api'
+    tier: backend
+
+spec:
+  replicas: 1
+  selector:
+    matchLabels: *labels
+  ports: [80, 443]
+`,
+		},
+		{
+			description: "new field is added to the end",
+			path:        []types.PathElement{{ObjectField: "spec"}, {ObjectField: "paused"}},
+			value:       true,
+			expected: `# deployment
+kind: Deployment
+metadata:
+  name: app # the name
+  labels: &labels
+    app: 'web'
+
+spec:
+  replicas: 1
+  selector:
+    matchLabels: *labels
+  ports: [80, 443]
+  paused: true
+`,
+		},
+		{
+			description: "alias is replaced, anchor is left alone",
+			path:        []types.PathElement{{ObjectField: "spec"}, {ObjectField: "selector"}, {ObjectField: "matchLabels"}},
+			value:       map[string]any{"app": "other"},
+			expected: `# deployment
+kind: Deployment
+metadata:
+  name: app # the name
+  labels: &labels
+    app: 'web'
+
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app: other
+  ports: [80, 443]
+`,
+		},
+		{
+			description: "sequence is extended",
+			path:        []types.PathElement{{ObjectField: "spec"}, {ObjectField: "ports"}},
+			value:       []any{80, 443, 8080},
+			expected: `# deployment
+kind: Deployment
+metadata:
+  name: app # the name
+  labels: &labels
+    app: 'web'
+
+spec:
+  replicas: 1
+  selector:
+    matchLabels: *labels
+  ports: [80, 443, 8080]
+`,
+		},
+	}
+
+	for idx, ex := range examples {
+		root := MustParse([]byte(initial))
+
+		node := types.Node(root)
+		var err error
+
+		for _, p := range ex.path[:len(ex.path)-1] {
+			node, err = node.Visit(p)
+			assert.NoError(t, err, "[Ex %d - %s]", idx+1, ex.description)
+		}
+
+		err = node.SetField(ex.path[len(ex.path)-1], ex.value)
+		assert.NoError(t, err, "[Ex %d - %s]", idx+1, ex.description)
+
+		out, err := root.Serialize()
+		assert.NoError(t, err, "[Ex %d - %s]", idx+1, ex.description)
+
+		assert.Equal(t, ex.expected, string(out), "[Ex %d - %s]", idx+1, ex.description)
+	}
+}
+
+func TestDeleteField(t *testing.T) {
+	initial := `a: 1
+
+# about b
+b: 2
+
+c:
+  - x
+  - y
+`
+
+	examples := []struct {
+		description string
+		path        []types.PathElement
+		expected    string
+		isErr       bool
+	}{
+		{
+			description: "delete a key",
+			path:        []types.PathElement{{ObjectField: "a"}},
+			expected: `# about b
+b: 2
+
+c:
+  - x
+  - y
+`,
+		},
+		{
+			description: "delete array item",
+			path:        []types.PathElement{{ObjectField: "c"}, {ArrayIdx: 0}},
+			expected: `a: 1
+
+# about b
+b: 2
+
+c:
+  - y
+`,
+		},
+		{
+			description: "delete the last array item",
+			path:        []types.PathElement{{ObjectField: "c"}, {ArrayIdx: -1}},
+			expected: `a: 1
+
+# about b
+b: 2
+
+c:
+  - x
+`,
+		},
+		{
+			description: "delete missing key is fine",
+			path:        []types.PathElement{{ObjectField: "d"}},
+			expected:    initial,
+		},
+		{
+			description: "out of bounds",
+			path:        []types.PathElement{{ObjectField: "c"}, {ArrayIdx: 5}},
+			isErr:       true,
+		},
+	}
+
+	for idx, ex := range examples {
+		root := MustParse([]byte(initial))
+
+		node := types.Node(root)
+		var err error
+
+		for _, p := range ex.path[:len(ex.path)-1] {
+			node, err = node.Visit(p)
+			assert.NoError(t, err, "[Ex %d - %s]", idx+1, ex.description)
+		}
+
+		err = node.DeleteField(ex.path[len(ex.path)-1])
+
+		if ex.isErr {
+			assert.Error(t, err, "[Ex %d - %s]", idx+1, ex.description)
+			continue
+		} else {
+			assert.NoError(t, err, "[Ex %d - %s]", idx+1, ex.description)
+		}
+
+		out, err := root.Serialize()
+		assert.NoError(t, err, "[Ex %d - %s]", idx+1, ex.description)
+
+		assert.Equal(t, ex.expected, string(out), "[Ex %d - %s]", idx+1, ex.description)
+	}
+}
+
+func TestValue(t *testing.T) {
+	root := MustParse([]byte("a: &x [1, 'two', null]\nb: *x\n"))
+
+	assert.Equal(t, types.NodeTypeObject, root.NodeType())
+	assert.Equal[any](t, map[string]any{
+		"a": []any{1, "two", nil},
+		"b": []any{1, "two", nil},
+	}, root.Value())
+
+	b, err := root.Visit(types.PathElement{ObjectField: "b"})
+	assert.NoError(t, err)
+	assert.Equal(t, types.NodeTypeArray, b.NodeType())
+}
+
+func TestMultipleDocuments(t *testing.T) {
+	initial := `---
+# first
+kind: Deployment
+spec:
+  replicas: 1
+---
+kind: Service
+
+---
+kind: Deployment
+`
+
+	docs, err := ParseAll([]byte(initial))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(docs))
+
+	err = docs[2].SetField(types.PathElement{ObjectField: "kind"}, "StatefulSet")
+	assert.NoError(t, err)
+
+	nodes := []types.Node{}
+
+	for _, doc := range docs {
+		nodes = append(nodes, doc)
+	}
+
+	out, err := SerializeAll(nodes)
+	assert.NoError(t, err)
+
+	assert.Equal(t, `---
+# first
+kind: Deployment
+spec:
+  replicas: 1
+---
+kind: Service
+
+---
+kind: StatefulSet
+`, string(out))
+
+	empty, err := ParseAll(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(empty))
+	assert.Equal(t, types.NodeTypeNull, empty[0].NodeType())
+}
+
+func TestSnapshot(t *testing.T) {
+	initial := `# comment
+base: &base
+  arr: [1, 2] # inline
+b:
+  x: 1
+c: *base
+`
+
+	root := MustParse([]byte(initial))
+	restore := root.Snapshot()
+
+	assert.NoError(t, root.SetField(types.PathElement{ObjectField: "d"}, 1))
+	assert.NoError(t, root.DeleteField(types.PathElement{ObjectField: "b"}))
+
+	base, err := root.Visit(types.PathElement{ObjectField: "base"})
+	assert.NoError(t, err)
+	assert.NoError(t, base.SetField(types.PathElement{ObjectField: "arr"}, []any{3}))
+
+	restore()
+
+	out, err := root.Serialize()
+	assert.NoError(t, err)
+	assert.Equal(t, initial, string(out))
+
+	// aliases point to the restored anchor
+	assert.NoError(t, base.SetField(types.PathElement{ObjectField: "arr"}, []any{3}))
+
+	c, err := root.GetField(types.PathElement{ObjectField: "c"})
+	assert.NoError(t, err)
+	assert.Equal[any](t, map[string]any{"arr": []any{3}}, c)
+}
+
+func TestWriteThroughAlias(t *testing.T) {
+	initial := `base: &base
+  x: 1
+  list: [1]
+use: *base
+`
+
+	examples := []struct {
+		description string
+		modify      func(root types.Node) error
+		expected    string
+	}{
+		{
+			description: "set a field",
+			modify: func(root types.Node) error {
+				use, err := root.Visit(types.Field("use"))
+
+				if err != nil {
+					return err
+				}
+
+				return use.SetField(types.Field("x"), 7)
+			},
+			expected: `base: &base
+  x: 1
+  list: [1]
+use:
+  x: 7
+  list: [1]
+`,
+		},
+		{
+			description: "delete a field",
+			modify: func(root types.Node) error {
+				use, err := root.Visit(types.Field("use"))
+
+				if err != nil {
+					return err
+				}
+
+				return use.DeleteField(types.Field("x"))
+			},
+			expected: `base: &base
+  x: 1
+  list: [1]
+use:
+  list: [1]
+`,
+		},
+		{
+			description: "insert into a nested sequence",
+			modify: func(root types.Node) error {
+				use, err := root.Visit(types.Field("use"))
+
+				if err != nil {
+					return err
+				}
+
+				list, err := use.Visit(types.Field("list"))
+
+				if err != nil {
+					return err
+				}
+
+				return list.InsertField(types.PathElement{ArrayIdx: 0}, 0)
+			},
+			expected: `base: &base
+  x: 1
+  list: [1]
+use:
+  x: 1
+  list: [0, 1]
+`,
+		},
+	}
+
+	for idx, ex := range examples {
+		root := MustParse([]byte(initial))
+
+		err := ex.modify(root)
+		assert.NoError(t, err, "[Ex %d - %s]", idx+1, ex.description)
+
+		out, err := root.Serialize()
+		assert.NoError(t, err, "[Ex %d - %s]", idx+1, ex.description)
+
+		assert.Equal(t, ex.expected, string(out), "[Ex %d - %s]", idx+1, ex.description)
+	}
+}
+
+func TestSerializeKeepsLayout(t *testing.T) {
+	examples := []struct {
+		description string
+		initial     string
+		path        []types.PathElement
+		value       any
+		expected    string
+	}{
+		{
+			description: "kubectl style sequences and comment spacing",
+			initial: `spec:
+  containers:
+  - name: app   # main
+    image: app:1
+    ports:
+    - 80    # http
+  - name: sidecar
+    image: proxy:1
+`,
+			path:  []types.PathElement{{ObjectField: "spec"}, {ObjectField: "containers"}, {ArrayIdx: 1}, {ObjectField: "image"}},
+			value: "proxy:2",
+			expected: `spec:
+  containers:
+  - name: app   # main
+    image: app:1
+    ports:
+    - 80    # http
+  - name: sidecar
+    image: proxy:2
+`,
+		},
+		{
+			description: "indented sequences stay indented",
+			initial: `spec:
+  containers:
+    - name: app
+      ports:
+        - 80
+`,
+			path:  []types.PathElement{{ObjectField: "spec"}, {ObjectField: "containers"}, {ArrayIdx: 0}, {ObjectField: "name"}},
+			value: "api",
+			expected: `spec:
+  containers:
+    - name: api
+      ports:
+        - 80
+`,
+		},
+		{
+			description: "literal blocks inside of compact sequences",
+			initial: `args:
+- |
+  - not an item
+- b
+`,
+			path:  []types.PathElement{{ObjectField: "args"}, {ArrayIdx: 1}},
+			value: "c",
+			expected: `args:
+- |
+  - not an item
+- c
+`,
+		},
+		{
+			description: "merge keys",
+			initial: `base: &base
+  a: 1
+child:
+  <<: *base
+  b: 2
+`,
+			path:  []types.PathElement{{ObjectField: "child"}, {ObjectField: "b"}},
+			value: 3,
+			expected: `base: &base
+  a: 1
+child:
+  <<: *base
+  b: 3
+`,
+		},
+		{
+			description: "trailing whitespace is kept and does not move blank lines",
+			initial:     "a: 1\n\nb: \"x\"   \nc: 2\n",
+			path:        []types.PathElement{{ObjectField: "c"}},
+			value:       3,
+			expected:    "a: 1\n\nb: \"x\"   \nc: 3\n",
+		},
+	}
+
+	for idx, ex := range examples {
+		root := MustParse([]byte(ex.initial))
+
+		node := types.Node(root)
+		var err error
+
+		for _, p := range ex.path[:len(ex.path)-1] {
+			node, err = node.Visit(p)
+			assert.NoError(t, err, "[Ex %d - %s]", idx+1, ex.description)
+		}
+
+		err = node.SetField(ex.path[len(ex.path)-1], ex.value)
+		assert.NoError(t, err, "[Ex %d - %s]", idx+1, ex.description)
+
+		out, err := root.Serialize()
+		assert.NoError(t, err, "[Ex %d - %s]", idx+1, ex.description)
+
+		assert.Equal(t, ex.expected, string(out), "[Ex %d - %s]", idx+1, ex.description)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	examples := []struct {
+		description string
+		input       string
+	}{
+		{
+			description: "folded scalars",
+			input: `description: >
+  first line
+  continues here
+
+  second paragraph
+next: 1
+`,
+		},
+		{
+			description: "mixed sequence indentation",
+			input: `compact:
+- a
+- b
+indented:
+  - c
+  - d
+`,
+		},
+		{
+			description: "sequence indentation differs between documents",
+			input: `a:
+  - b
+---
+c:
+- d
+`,
+		},
+		{
+			description: "mixed mapping indentation with block scalars",
+			input: `two:
+  script: |
+    echo 1
+four:
+    script: |
+        echo 2
+`,
+		},
+		{
+			description: "document end markers",
+			input: `a: 1
+...
+---
+b: 2
+...
+`,
+		},
+		{
+			description: "crlf line endings",
+			input:       "# comment\r\na: 1\r\n\r\nb:\r\n  - c\r\n",
+		},
+	}
+
+	for idx, ex := range examples {
+		docs, err := ParseAll([]byte(ex.input))
+		assert.NoError(t, err, "[Ex %d - %s]", idx+1, ex.description)
+
+		nodes := []types.Node{}
+
+		for _, doc := range docs {
+			nodes = append(nodes, doc)
+		}
+
+		out, err := SerializeAll(nodes)
+		assert.NoError(t, err, "[Ex %d - %s]", idx+1, ex.description)
+
+		assert.Equal(t, ex.input, string(out), "[Ex %d - %s]", idx+1, ex.description)
+	}
+}